@@ -0,0 +1,366 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/charignon/umcp/internal/executor"
+)
+
+// Redacted replaces sensitive values in audit records
+const Redacted = executor.Redacted
+
+// Client identifies the MCP client that issued a tool call
+type Client struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Decision records the outcome of the security policy check
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Record is a single audit log entry. Hash covers every other field,
+// including PrevHash, so records form a tamper-evident chain.
+type Record struct {
+	Seq          int64                  `json:"seq"`
+	Timestamp    time.Time              `json:"timestamp"`
+	SessionID    string                 `json:"session_id"`
+	Client       Client                 `json:"client"`
+	Tool         string                 `json:"tool"`
+	Arguments    map[string]interface{} `json:"arguments,omitempty"`
+	Argv         []string               `json:"argv,omitempty"`
+	WorkingDir   string                 `json:"working_dir,omitempty"`
+	ExitCode     *int                   `json:"exit_code,omitempty"`
+	DurationMs   float64                `json:"duration_ms"`
+	OutputSHA256 string                 `json:"output_sha256,omitempty"`
+	Security     *Decision              `json:"security,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+	PrevHash     string                 `json:"prev_hash"`
+	Hash         string                 `json:"hash"`
+}
+
+// Logger writes hash-chained audit records. It implements executor.Tracer
+// and executor.StageTracer to collect the argv, working directory, exit code
+// and security decision of the call in progress; the server brackets each
+// tool call with StartCall and FinishCall.
+type Logger struct {
+	mu        sync.Mutex
+	file      *os.File
+	sessionID string
+	seq       int64
+	prevHash  string
+	current   *Record
+	secrets   []string
+}
+
+// NewLogger opens (or creates) an audit log and continues its hash chain
+func NewLogger(path, sessionID string) (*Logger, error) {
+	seq, prevHash, err := lastRecord(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return &Logger{
+		file:      file,
+		sessionID: sessionID,
+		seq:       seq,
+		prevHash:  prevHash,
+	}, nil
+}
+
+// StartCall begins a record for a tool call. Arguments listed in sensitive,
+// or whose names look like credentials, are redacted.
+func (l *Logger) StartCall(client Client, tool string, args map[string]interface{}, sensitive []string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	redact := make(map[string]bool, len(sensitive))
+	for _, name := range sensitive {
+		redact[name] = true
+	}
+
+	l.secrets = nil
+	redacted := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		if redact[name] || config.SensitiveName.MatchString(name) {
+			redacted[name] = Redacted
+			if s := fmt.Sprintf("%v", value); s != "" {
+				l.secrets = append(l.secrets, s)
+			}
+			continue
+		}
+		redacted[name] = value
+	}
+
+	l.current = &Record{
+		Timestamp: time.Now().UTC(),
+		SessionID: l.sessionID,
+		Client:    client,
+		Tool:      tool,
+		Arguments: redacted,
+	}
+}
+
+// FinishCall completes the current record and appends it to the log
+func (l *Logger) FinishCall(callErr error) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record := l.current
+	l.current = nil
+	if record == nil {
+		return nil
+	}
+
+	if callErr != nil {
+		record.Error = l.redact(callErr.Error())
+	}
+
+	l.seq++
+	record.Seq = l.seq
+	record.PrevHash = l.prevHash
+	hash, err := recordHash(record)
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	if _, err := fmt.Fprintf(l.file, "%s\n", data); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+
+	l.prevHash = hash
+	return nil
+}
+
+// TraceCommand records the exact argv and working directory
+func (l *Logger) TraceCommand(command string, args []string, workingDir string, env []string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.current == nil {
+		return
+	}
+	argv := make([]string, 0, len(args)+1)
+	for _, part := range append([]string{command}, args...) {
+		argv = append(argv, l.redact(part))
+	}
+	l.current.Argv = argv
+	l.current.WorkingDir = workingDir
+}
+
+// TraceCommandOutput records the exit code and a hash of the output
+func (l *Logger) TraceCommandOutput(output string, exitCode int, err error) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.current == nil {
+		return
+	}
+	sum := sha256.Sum256([]byte(output))
+	l.current.OutputSHA256 = hex.EncodeToString(sum[:])
+	l.current.ExitCode = &exitCode
+}
+
+// TraceStage records the security decision and execution duration
+func (l *Logger) TraceStage(stage executor.Stage) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.current == nil {
+		return
+	}
+	switch stage.Name {
+	case "sandbox":
+		decision := &Decision{Allowed: stage.Err == nil}
+		if stage.Err != nil {
+			decision.Reason = l.redact(stage.Err.Error())
+		}
+		l.current.Security = decision
+	case "exec":
+		l.current.DurationMs = float64(stage.Duration.Microseconds()) / 1000
+		if code, ok := stage.Attributes["exit_code"].(int); ok {
+			l.current.ExitCode = &code
+		}
+	}
+}
+
+// Close closes the audit log
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// redact replaces sensitive argument values in s. Callers must hold l.mu.
+func (l *Logger) redact(s string) string {
+	for _, secret := range l.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+// Verify checks the hash chain of an audit log and returns the number of
+// valid records
+func Verify(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	prevHash := ""
+	var seq int64
+	count := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		count++
+
+		record, err := decodeRecord(line)
+		if err != nil {
+			return count - 1, fmt.Errorf("record %d: %w", count, err)
+		}
+		if record.PrevHash != prevHash {
+			return count - 1, fmt.Errorf("record %d: chain broken, expected prev_hash %q, got %q", count, prevHash, record.PrevHash)
+		}
+		if seq > 0 && record.Seq != seq+1 {
+			return count - 1, fmt.Errorf("record %d: expected seq %d, got %d", count, seq+1, record.Seq)
+		}
+
+		hash, err := recordHash(record)
+		if err != nil {
+			return count - 1, fmt.Errorf("record %d: %w", count, err)
+		}
+		if hash != record.Hash {
+			return count - 1, fmt.Errorf("record %d: hash mismatch, record has been modified", count)
+		}
+
+		prevHash = record.Hash
+		seq = record.Seq
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return count, nil
+}
+
+// VerifyFile checks the hash chain of the audit log at path
+func VerifyFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+	return Verify(file)
+}
+
+// recordHash computes the chained hash of a record, ignoring its Hash field
+func recordHash(record *Record) (string, error) {
+	unhashed := *record
+	unhashed.Hash = ""
+	data, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// decodeRecord parses a record, keeping numbers in their original form so
+// the hash can be recomputed byte for byte
+func decodeRecord(line []byte) (*Record, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var record Record
+	if err := decoder.Decode(&record); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return &record, nil
+}
+
+// lastRecord returns the sequence number and hash of the last record in an
+// existing audit log
+func lastRecord(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	var last []byte
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, "", fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if last == nil {
+		return 0, "", nil
+	}
+
+	record, err := decodeRecord(last)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read last audit record: %w", err)
+	}
+	return record.Seq, record.Hash, nil
+}
@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charignon/umcp/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCall(t *testing.T, logger *Logger, tool string, args map[string]interface{}, sensitive []string, callErr error) {
+	t.Helper()
+	logger.StartCall(Client{Name: "claude", Version: "1.0"}, tool, args, sensitive)
+	logger.TraceStage(executor.Stage{Name: "sandbox"})
+	logger.TraceCommand("git", []string{"commit", "-m", "hello", "--token=s3cr3t"}, "/repo", nil)
+	logger.TraceCommandOutput("done", 0, nil)
+	logger.TraceStage(executor.Stage{
+		Name:       "exec",
+		Duration:   1500 * time.Microsecond,
+		Attributes: map[string]interface{}{"exit_code": 0},
+	})
+	require.NoError(t, logger.FinishCall(callErr))
+}
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLoggerWritesChainedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := NewLogger(path, "session-1")
+	require.NoError(t, err)
+
+	args := map[string]interface{}{"message": "hello", "token": "s3cr3t", "limit": float64(5)}
+	writeCall(t, logger, "git_commit", args, nil, nil)
+	writeCall(t, logger, "git_commit", map[string]interface{}{"message": "hello"}, []string{"message"}, errors.New("failed: hello"))
+	require.NoError(t, logger.Close())
+
+	records := readRecords(t, path)
+	require.Len(t, records, 2)
+
+	first := records[0]
+	assert.Equal(t, int64(1), first.Seq)
+	assert.Equal(t, "session-1", first.SessionID)
+	assert.Equal(t, Client{Name: "claude", Version: "1.0"}, first.Client)
+	assert.Equal(t, "git_commit", first.Tool)
+	assert.Equal(t, Redacted, first.Arguments["token"])
+	assert.Equal(t, "hello", first.Arguments["message"])
+	assert.Equal(t, []string{"git", "commit", "-m", "hello", "--token=" + Redacted}, first.Argv)
+	assert.Equal(t, "/repo", first.WorkingDir)
+	require.NotNil(t, first.ExitCode)
+	assert.Equal(t, 0, *first.ExitCode)
+	assert.Equal(t, 1.5, first.DurationMs)
+	assert.NotEmpty(t, first.OutputSHA256)
+	assert.Equal(t, &Decision{Allowed: true}, first.Security)
+	assert.Empty(t, first.PrevHash)
+
+	second := records[1]
+	assert.Equal(t, first.Hash, second.PrevHash)
+	assert.Equal(t, Redacted, second.Arguments["message"])
+	assert.Contains(t, second.Argv, Redacted)
+	assert.Equal(t, "failed: "+Redacted, second.Error)
+
+	count, err := VerifyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestLoggerContinuesExistingChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	logger, err := NewLogger(path, "session-1")
+	require.NoError(t, err)
+	writeCall(t, logger, "ls_list", nil, nil, nil)
+	require.NoError(t, logger.Close())
+
+	logger, err = NewLogger(path, "session-2")
+	require.NoError(t, err)
+	writeCall(t, logger, "ls_list", nil, nil, nil)
+	require.NoError(t, logger.Close())
+
+	records := readRecords(t, path)
+	require.Len(t, records, 2)
+	assert.Equal(t, int64(2), records[1].Seq)
+	assert.Equal(t, records[0].Hash, records[1].PrevHash)
+
+	count, err := VerifyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := NewLogger(path, "session-1")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		writeCall(t, logger, "git_status", map[string]interface{}{"short": true}, nil, nil)
+	}
+	require.NoError(t, logger.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	tests := []struct {
+		name        string
+		lines       []string
+		expectError string
+	}{
+		{
+			name:        "modified record",
+			lines:       []string{lines[0], strings.Replace(lines[1], "git_status", "git_push", 1), lines[2]},
+			expectError: "record 2: hash mismatch",
+		},
+		{
+			name:        "deleted record",
+			lines:       []string{lines[0], lines[2]},
+			expectError: "record 2: chain broken",
+		},
+		{
+			name:        "reordered records",
+			lines:       []string{lines[1], lines[0], lines[2]},
+			expectError: "record 1: chain broken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(strings.Join(tt.lines, "\n")))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectError)
+		})
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var logger *Logger
+	logger.StartCall(Client{}, "x", nil, nil)
+	logger.TraceCommand("x", nil, "", nil)
+	logger.TraceStage(executor.Stage{Name: "exec"})
+	assert.NoError(t, logger.FinishCall(nil))
+	assert.NoError(t, logger.Close())
+}
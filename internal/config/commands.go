@@ -0,0 +1,237 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ResolveCommand finds a command through PATH and returns its absolute
+// path with symlinks evaluated
+func ResolveCommand(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+// AllowedCommandPaths returns the allowed commands resolved through PATH.
+// They are resolved once, at load time.
+func (s *Security) AllowedCommandPaths() ([]string, error) {
+	if s.allowedCommands == nil && len(s.AllowedCommands) > 0 {
+		paths := make([]string, 0, len(s.AllowedCommands))
+		for _, name := range s.AllowedCommands {
+			path, err := ResolveCommand(name)
+			if err != nil {
+				return nil, fmt.Errorf("allowed command %s: %w", name, err)
+			}
+			paths = append(paths, path)
+		}
+		s.allowedCommands = paths
+	}
+	return s.allowedCommands, nil
+}
+
+// IsCommandAllowed checks a command against allowed_commands. Every
+// command is allowed when the list is empty; otherwise the command must
+// resolve to the same binary as one of the entries.
+func (s *Security) IsCommandAllowed(command string) bool {
+	if len(s.AllowedCommands) == 0 {
+		return true
+	}
+	allowed, err := s.AllowedCommandPaths()
+	if err != nil {
+		return false
+	}
+	path, err := ResolveCommand(command)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		if path == a {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSubcommandAllowed checks a subcommand against allowed_subcommands.
+// Every subcommand is allowed when the list is empty.
+func (s *Security) IsSubcommandAllowed(subcommand string) bool {
+	if len(s.AllowedSubcommands) == 0 {
+		return true
+	}
+	for _, allowed := range s.AllowedSubcommands {
+		if subcommand == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// DeniedFlagRegexps returns the compiled denied flag patterns
+func (s *Security) DeniedFlagRegexps() ([]*regexp.Regexp, error) {
+	if s.deniedFlags == nil && len(s.DeniedFlags) > 0 {
+		res := make([]*regexp.Regexp, 0, len(s.DeniedFlags))
+		for _, pattern := range s.DeniedFlags {
+			re, err := globRegexp(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("denied flag %s: %w", pattern, err)
+			}
+			res = append(res, re)
+		}
+		s.deniedFlags = res
+	}
+	return s.deniedFlags, nil
+}
+
+// DeniedFlag returns the denied flag pattern an argument matches, or ""
+// if none does. An argument like --upload-pack=x is also matched by its
+// flag part, --upload-pack. Patterns that fail to compile match
+// everything, so a broken policy never lets a flag through.
+func (s *Security) DeniedFlag(arg string) string {
+	if len(s.DeniedFlags) == 0 {
+		return ""
+	}
+	res, err := s.DeniedFlagRegexps()
+	if err != nil {
+		return s.DeniedFlags[0]
+	}
+	flag := arg
+	if idx := strings.IndexByte(arg, '='); idx > 0 {
+		flag = arg[:idx]
+	}
+	for i, re := range res {
+		if re.MatchString(arg) || re.MatchString(flag) {
+			return s.DeniedFlags[i]
+		}
+	}
+	return ""
+}
+
+// globRegexp compiles a glob pattern where * matches any run of characters
+// and ? any single character
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	if !strings.HasPrefix(pattern, "-") {
+		return nil, fmt.Errorf("pattern must start with -")
+	}
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return regexp.Compile("^" + quoted + "$")
+}
+
+// commandPin is the binary settings.command resolved to at startup
+type commandPin struct {
+	path string
+
+	mu      sync.Mutex
+	size    int64     // Size and modification time when the digest was
+	modTime time.Time // last checked
+}
+
+// Preflight resolves settings.command through PATH once, at startup, and
+// pins the binary so later PATH changes do not change what runs. With
+// command_sha256 set the binary's digest must match. The command must also
+// be allowed when allowed_commands is set. A command that cannot be found
+// is only an error under one of those policies; otherwise it is logged and
+// looked up again each time a tool runs, so one missing CLI does not stop
+// a server with several configs.
+func (c *Config) Preflight() error {
+	if c.Settings.Command == "" {
+		return nil
+	}
+	path, err := ResolveCommand(c.Settings.Command)
+	if err != nil {
+		if len(c.Security.AllowedCommands) > 0 || c.Settings.CommandSHA256 != "" {
+			return fmt.Errorf("settings.command: %w", err)
+		}
+		log.Warn().Err(err).
+			Str("config", c.Metadata.Name).
+			Msg("Command not found; it will be looked up when a tool runs")
+		return nil
+	}
+	if !c.Security.IsCommandAllowed(path) {
+		return fmt.Errorf("settings.command: %s is not in allowed_commands", path)
+	}
+
+	pin := &commandPin{path: path}
+	if c.Settings.CommandSHA256 != "" {
+		if err := pin.verify(c.Settings.CommandSHA256); err != nil {
+			return fmt.Errorf("settings.command: %w", err)
+		}
+	}
+	c.Settings.pin = pin
+	return nil
+}
+
+// Executable returns the pinned path of settings.command after Preflight,
+// or the command as written before
+func (s *Settings) Executable() string {
+	if s.pin != nil {
+		return s.pin.path
+	}
+	return s.Command
+}
+
+// VerifyCommand checks the pinned binary against command_sha256 again. The
+// digest is only recomputed when the file's size or modification time has
+// changed since the last check.
+func (s *Settings) VerifyCommand() error {
+	if s.pin == nil || s.CommandSHA256 == "" {
+		return nil
+	}
+	return s.pin.verify(s.CommandSHA256)
+}
+
+func (p *commandPin) verify(expected string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	if info.Size() == p.size && info.ModTime().Equal(p.modTime) {
+		return nil
+	}
+
+	digest, err := fileSHA256(p.path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(digest, expected) {
+		return fmt.Errorf("%s has sha256 %s, expected %s", p.path, digest, strings.ToLower(expected))
+	}
+	p.size, p.modTime = info.Size(), info.ModTime()
+	return nil
+}
+
+// fileSHA256 returns the hex SHA-256 digest of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCommandAllowed(t *testing.T) {
+	sh, err := ResolveCommand("sh")
+	require.NoError(t, err)
+
+	security := &Security{AllowedCommands: []string{"sh"}}
+	assert.True(t, security.IsCommandAllowed("sh"))
+	assert.True(t, security.IsCommandAllowed(sh))
+	assert.False(t, security.IsCommandAllowed("umcp-no-such-command"))
+	assert.True(t, (&Security{}).IsCommandAllowed("anything"))
+
+	// A binary of the same name elsewhere on disk is not the allowed one
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "sh")
+	require.NoError(t, os.WriteFile(fake, []byte("#!/bin/sh\n"), 0755))
+	assert.False(t, security.IsCommandAllowed(fake))
+}
+
+func TestDeniedFlag(t *testing.T) {
+	security := &Security{DeniedFlags: []string{"--exec", "-c*", "--upload-pack"}}
+
+	assert.Equal(t, "--exec", security.DeniedFlag("--exec"))
+	assert.Equal(t, "-c*", security.DeniedFlag("-c"))
+	assert.Equal(t, "-c*", security.DeniedFlag("-ccore.pager=/tmp/x"))
+	assert.Equal(t, "--upload-pack", security.DeniedFlag("--upload-pack=/tmp/evil"))
+	assert.Equal(t, "", security.DeniedFlag("--execute"))
+	assert.Equal(t, "", security.DeniedFlag("--color"))
+	assert.Equal(t, "", security.DeniedFlag("exec"))
+}
+
+func TestPreflight(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "tool")
+	require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\necho one\n"), 0755))
+	sum := sha256.Sum256([]byte("#!/bin/sh\necho one\n"))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := &Config{Settings: Settings{Command: "tool", CommandSHA256: hex.EncodeToString(sum[:])}}
+	require.NoError(t, cfg.Preflight())
+	resolved, err := filepath.EvalSymlinks(bin)
+	require.NoError(t, err)
+	assert.Equal(t, resolved, cfg.Settings.Executable())
+	assert.NoError(t, cfg.Settings.VerifyCommand())
+
+	// Replacing the binary is caught on the next check
+	require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\necho two\n"), 0755))
+	require.NoError(t, os.Chtimes(bin, time.Now(), time.Now().Add(time.Minute)))
+	assert.ErrorContains(t, cfg.Settings.VerifyCommand(), "expected "+hex.EncodeToString(sum[:]))
+
+	wrong := &Config{Settings: Settings{Command: "tool", CommandSHA256: hex.EncodeToString(sum[:])}}
+	assert.ErrorContains(t, wrong.Preflight(), "settings.command: "+resolved+" has sha256")
+
+	// A missing command is only an error under an allowlist or a digest
+	missing := &Config{Settings: Settings{Command: "umcp-no-such-command"}}
+	assert.NoError(t, missing.Preflight())
+	assert.Equal(t, "umcp-no-such-command", missing.Settings.Executable())
+	missing.Settings.CommandSHA256 = hex.EncodeToString(sum[:])
+	assert.ErrorContains(t, missing.Preflight(), "settings.command:")
+	missing.Settings.CommandSHA256 = ""
+	missing.Security.AllowedCommands = []string{"sh"}
+	assert.ErrorContains(t, missing.Preflight(), "settings.command:")
+
+	notAllowed := &Config{
+		Settings: Settings{Command: "tool"},
+		Security: Security{AllowedCommands: []string{"sh"}},
+	}
+	assert.EqualError(t, notAllowed.Preflight(), "settings.command: "+resolved+" is not in allowed_commands")
+
+	// Script-only configs have no command to pin
+	assert.NoError(t, (&Config{}).Preflight())
+}
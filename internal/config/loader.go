@@ -1,10 +1,17 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/charignon/umcp/internal/expr"
+	"github.com/charignon/umcp/internal/schema"
+	"github.com/charignon/umcp/internal/template"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,6 +27,10 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
+	if err := cfg.loadSchemaFiles(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to load output schema: %w", err)
+	}
+
 	// Apply defaults and validate
 	if err := cfg.applyDefaults(); err != nil {
 		return nil, fmt.Errorf("failed to apply defaults: %w", err)
@@ -32,6 +43,33 @@ func LoadConfig(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// loadSchemaFiles reads output schema files, resolving relative paths
+// against the directory of the config file
+func (c *Config) loadSchemaFiles(baseDir string) error {
+	for i := range c.Tools {
+		output := &c.Tools[i].Output
+		if output.SchemaFile == "" {
+			continue
+		}
+		if output.Schema != nil {
+			return fmt.Errorf("tool %s: schema and schema_file cannot both be set", c.Tools[i].Name)
+		}
+
+		path := output.SchemaFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("tool %s: %w", c.Tools[i].Name, err)
+		}
+		if err := yaml.Unmarshal(data, &output.Schema); err != nil {
+			return fmt.Errorf("tool %s: %s: %w", c.Tools[i].Name, output.SchemaFile, err)
+		}
+	}
+	return nil
+}
+
 // applyDefaults sets default values for optional fields
 func (c *Config) applyDefaults() error {
 	if c.Version == "" {
@@ -46,6 +84,14 @@ func (c *Config) applyDefaults() error {
 		c.Settings.Timeout = 30 * time.Second
 	}
 
+	if c.Settings.CallTimeout == 0 {
+		c.Settings.CallTimeout = 3 * c.Settings.Timeout
+	}
+
+	if c.Settings.Coercion == "" {
+		c.Settings.Coercion = "lenient"
+	}
+
 	if c.Security.MaxOutputSize == 0 {
 		c.Security.MaxOutputSize = 10 * 1024 * 1024 // 10MB default
 	}
@@ -57,34 +103,98 @@ func (c *Config) applyDefaults() error {
 			tool.Output.Type = "raw"
 		}
 
+		if retry := tool.Retry; retry != nil {
+			if retry.MaxAttempts == 0 {
+				retry.MaxAttempts = 3
+			}
+			if retry.Backoff == 0 {
+				retry.Backoff = time.Second
+			}
+			if retry.Multiplier == 0 {
+				retry.Multiplier = 2
+			}
+		}
+
 		// Apply argument defaults
 		for j := range tool.Arguments {
 			arg := &tool.Arguments[j]
 			if arg.Type == "" {
 				arg.Type = "string"
 			}
+			defaultValueTypes(arg.Items)
+			for _, property := range arg.Properties {
+				defaultValueTypes(property)
+			}
 		}
 	}
 
 	return nil
 }
 
+// defaultValueTypes makes untyped items and properties strings
+func defaultValueTypes(value *Value) {
+	if value == nil {
+		return
+	}
+	if value.Type == "" {
+		value.Type = "string"
+	}
+	defaultValueTypes(value.Items)
+	for _, property := range value.Properties {
+		defaultValueTypes(property)
+	}
+}
+
 // validate checks that the configuration is valid
 func (c *Config) validate() error {
 	if c.Metadata.Name == "" {
 		return fmt.Errorf("metadata.name is required")
 	}
 
+	// Tools with a script do not use settings.command
 	if c.Settings.Command == "" {
-		return fmt.Errorf("settings.command is required")
+		for _, tool := range c.Tools {
+			if tool.Script == "" {
+				return fmt.Errorf("settings.command is required")
+			}
+		}
 	}
 
 	if len(c.Tools) == 0 {
 		return fmt.Errorf("at least one tool must be defined")
 	}
 
+	if err := validateErrorRules(c.Errors); err != nil {
+		return fmt.Errorf("errors: %w", err)
+	}
+
 	// Validate each tool
-	for _, tool := range c.Tools {
+	if c.Settings.Shell != "" {
+		if err := validateShell(c.Settings.Shell); err != nil {
+			return fmt.Errorf("settings: %w", err)
+		}
+	}
+
+	if c.Settings.CallTimeout < c.Settings.Timeout {
+		return fmt.Errorf("settings: call_timeout must not be shorter than timeout")
+	}
+
+	if c.Settings.Coercion != "lenient" && c.Settings.Coercion != "strict" {
+		return fmt.Errorf("settings: invalid coercion %q (must be lenient or strict)", c.Settings.Coercion)
+	}
+
+	if sum := c.Settings.CommandSHA256; sum != "" {
+		if digest, err := hex.DecodeString(sum); err != nil || len(digest) != sha256.Size {
+			return fmt.Errorf("settings: command_sha256 must be 64 hex digits")
+		}
+	}
+
+	if err := validateSecurity(&c.Security); err != nil {
+		return fmt.Errorf("security: %w", err)
+	}
+
+	for i := range c.Tools {
+		tool := &c.Tools[i]
 		if tool.Name == "" {
 			return fmt.Errorf("tool name is required")
 		}
@@ -97,27 +207,91 @@ func (c *Config) validate() error {
 		validOutputTypes := map[string]bool{
 			"raw": true, "json": true, "lines": true,
 			"regex": true, "csv": true, "xml": true,
+			"yaml": true, "toml": true, "ndjson": true,
+			"logfmt": true, "keyvalue": true, "table": true,
+			"diff": true, "image": true, "file": true, "blob": true,
 		}
 		if !validOutputTypes[tool.Output.Type] {
 			return fmt.Errorf("tool %s: invalid output type %s", tool.Name, tool.Output.Type)
 		}
 
-		// If regex output, pattern is required
-		if tool.Output.Type == "regex" && tool.Output.Pattern == "" {
-			return fmt.Errorf("tool %s: pattern is required for regex output", tool.Name)
+		if tool.Output.Type == "regex" {
+			if err := validateRegexOutput(&tool.Output); err != nil {
+				return fmt.Errorf("tool %s: %w", tool.Name, err)
+			}
+		}
+
+		if _, err := tool.Output.JQFilter(); err != nil {
+			return fmt.Errorf("tool %s: %w", tool.Name, err)
+		}
+
+		if err := validateColumns(tool.Output.Columns); err != nil {
+			return fmt.Errorf("tool %s: columns: %w", tool.Name, err)
+		}
+
+		if err := validateBinaryOutput(tool); err != nil {
+			return fmt.Errorf("tool %s: %w", tool.Name, err)
+		}
+
+		if err := validateOutputSchema(&tool.Output); err != nil {
+			return fmt.Errorf("tool %s: %w", tool.Name, err)
+		}
+
+		if tool.Output.Type == "csv" {
+			if err := validateCSV(&tool.Output); err != nil {
+				return fmt.Errorf("tool %s: %w", tool.Name, err)
+			}
+		}
+
+		if tool.Output.MaxHunks < 0 || tool.Output.MaxLines < 0 {
+			return fmt.Errorf("tool %s: max_hunks and max_lines must not be negative", tool.Name)
+		}
+
+		// Validate success exit codes
+		for _, code := range tool.SuccessExitCodes {
+			if code < 0 || code > 255 {
+				return fmt.Errorf("tool %s: invalid success exit code %d", tool.Name, code)
+			}
+		}
+
+		if err := validateErrorRules(tool.Errors); err != nil {
+			return fmt.Errorf("tool %s: errors: %w", tool.Name, err)
+		}
+
+		if err := validateTransform(tool.Output.Transform); err != nil {
+			return fmt.Errorf("tool %s: transform: %w", tool.Name, err)
+		}
+
+		if err := validateCache(c, tool); err != nil {
+			return fmt.Errorf("tool %s: cache: %w", tool.Name, err)
+		}
+
+		if err := validateRetry(tool.Retry); err != nil {
+			return fmt.Errorf("tool %s: retry: %w", tool.Name, err)
+		}
+
+		if err := validateScript(c, tool); err != nil {
+			return fmt.Errorf("tool %s: %w", tool.Name, err)
+		}
+
+		if err := validateToolConditions(tool); err != nil {
+			return fmt.Errorf("tool %s: %w", tool.Name, err)
+		}
+
+		for j, constraint := range tool.Constraints {
+			if err := validateConstraint(tool, constraint); err != nil {
+				return fmt.Errorf("tool %s: constraint %d: %w", tool.Name, j+1, err)
+			}
 		}
 
 		// Validate arguments
-		for _, arg := range tool.Arguments {
+		for j := range tool.Arguments {
+			arg := &tool.Arguments[j]
 			if arg.Name == "" {
 				return fmt.Errorf("tool %s: argument name is required", tool.Name)
 			}
 
 			// Validate argument type
-			validArgTypes := map[string]bool{
-				"string": true, "boolean": true, "integer": true,
-				"array": true, "object": true, "float": true,
-			}
 			if !validArgTypes[arg.Type] {
 				return fmt.Errorf("tool %s, argument %s: invalid type %s", tool.Name, arg.Name, arg.Type)
 			}
@@ -126,8 +300,485 @@ func (c *Config) validate() error {
 			if arg.Required && arg.Default != nil {
 				return fmt.Errorf("tool %s, argument %s: required arguments cannot have defaults", tool.Name, arg.Name)
 			}
+
+			if err := validateArgumentRendering(*arg); err != nil {
+				return fmt.Errorf("tool %s, argument %s: %w", tool.Name, arg.Name, err)
+			}
+
+			if err := validateArgumentValues(arg); err != nil {
+				return fmt.Errorf("tool %s, argument %s: %w", tool.Name, arg.Name, err)
+			}
+
+			condition, err := arg.Condition()
+			if err == nil {
+				err = validateCondition(tool, condition)
+			}
+			if err != nil {
+				return fmt.Errorf("tool %s, argument %s: when: %w", tool.Name, arg.Name, err)
+			}
+		}
+
+		for i, chain := range tool.Chain {
+			for _, text := range chain.Arguments {
+				if err := template.Check(text); err != nil {
+					return fmt.Errorf("tool %s: chain step %d: %w", tool.Name, i+1, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateSecurity resolves allowed commands and checks the subcommand
+// and denied flag lists
+func validateSecurity(security *Security) error {
+	if _, err := security.AllowedCommandPaths(); err != nil {
+		return err
+	}
+	for _, subcommand := range security.AllowedSubcommands {
+		if subcommand == "" || strings.HasPrefix(subcommand, "-") {
+			return fmt.Errorf("invalid allowed subcommand %q", subcommand)
+		}
+	}
+	if _, err := security.DeniedFlagRegexps(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateColumns checks table column specs
+func validateColumns(columns []Group) error {
+	for i, col := range columns {
+		if col.Name == "" {
+			return fmt.Errorf("column %d: name is required", i+1)
+		}
+		if col.Start < 0 || col.End < 0 {
+			return fmt.Errorf("column %s: start and end must not be negative", col.Name)
+		}
+		if col.End != 0 && col.End <= col.Start {
+			return fmt.Errorf("column %s: end must be greater than start", col.Name)
+		}
+	}
+	return nil
+}
+
+// validateRegexOutput checks and compiles the patterns of regex output
+func validateRegexOutput(output *Output) error {
+	// If regex output, pattern is required
+	if output.Pattern == "" && len(output.Patterns) == 0 {
+		return fmt.Errorf("pattern is required for regex output")
+	}
+	if _, err := output.RecordSeparatorRegexp(); err != nil {
+		return fmt.Errorf("invalid record separator: %w", err)
+	}
+	if output.RecordSeparator == "" && output.Pattern != "" && len(output.Patterns) > 0 {
+		return fmt.Errorf("pattern and patterns cannot both be set without a record separator")
+	}
+	if _, err := output.Regexp(); err != nil {
+		return fmt.Errorf("invalid regex pattern: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range output.Patterns {
+		p := &output.Patterns[i]
+		if p.Pattern == "" {
+			return fmt.Errorf("patterns: entry %d: pattern is required", i+1)
+		}
+		if output.RecordSeparator == "" {
+			if p.Name == "" {
+				return fmt.Errorf("patterns: entry %d: name is required", i+1)
+			}
+			if names[p.Name] {
+				return fmt.Errorf("patterns: duplicate name %s", p.Name)
+			}
+			names[p.Name] = true
+		}
+		if _, err := p.Regexp(); err != nil {
+			return fmt.Errorf("patterns: entry %d: invalid regex pattern: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateBinaryOutput checks where image, file and blob output is read from
+func validateBinaryOutput(tool *Tool) error {
+	output := &tool.Output
+	if !output.IsBinary() {
+		if output.File != "" || output.FileArg != "" {
+			return fmt.Errorf("file and file_arg require image, file or blob output")
+		}
+		return nil
+	}
+	if output.Type == "file" && output.File == "" && output.FileArg == "" {
+		return fmt.Errorf("file output requires file or file_arg")
+	}
+	if output.Type == "blob" && (output.File != "" || output.FileArg != "") {
+		return fmt.Errorf("blob output is read from stdout; use file output instead")
+	}
+	if output.Schema != nil || output.SchemaFile != "" {
+		return fmt.Errorf("%s output cannot have a schema", output.Type)
+	}
+	if output.FileArg == "" {
+		return nil
+	}
+	for _, arg := range tool.Arguments {
+		if arg.Name == output.FileArg {
+			if arg.Type != "string" && arg.Type != "path" {
+				return fmt.Errorf("file_arg %s must be a string or path argument", arg.Name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("file_arg %s is not an argument of the tool", output.FileArg)
+}
+
+// validateOutputSchema normalizes and checks the output schema and the
+// mismatch policy
+func validateOutputSchema(output *Output) error {
+	switch output.OnMismatch {
+	case "", "fail", "warn", "pass":
+	default:
+		return fmt.Errorf("invalid on_mismatch %s (must be fail, warn or pass)", output.OnMismatch)
+	}
+	if output.Schema == nil {
+		return nil
+	}
+
+	normalized, err := schema.Normalize(output.Schema)
+	if err != nil {
+		return fmt.Errorf("output schema: %w", err)
+	}
+	if err := schema.Check(normalized); err != nil {
+		return fmt.Errorf("output schema: %w", err)
+	}
+	output.Schema = normalized
+	return nil
+}
+
+// validateCache checks a tool's cache settings. Tools that write to a
+// file_arg path cannot be cached, since a cached call would not write it.
+func validateCache(c *Config, tool *Tool) error {
+	if tool.Cache == nil {
+		return nil
+	}
+	if tool.Cache.TTL <= 0 {
+		return fmt.Errorf("ttl must be positive")
+	}
+	if tool.Output.FileArg != "" {
+		return fmt.Errorf("tools with file_arg output cannot be cached")
+	}
+	for _, pattern := range tool.Cache.Watch {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid watch pattern %q: %w", pattern, err)
+		}
+	}
+	toolNames := make(map[string]bool, len(c.Tools))
+	for _, t := range c.Tools {
+		toolNames[t.Name] = true
+	}
+	for _, name := range tool.Cache.InvalidatedBy {
+		if !toolNames[name] {
+			return fmt.Errorf("invalidated_by: unknown tool %s", name)
+		}
+	}
+	return nil
+}
+
+// validateRetry checks a retry policy and compiles its stderr patterns
+func validateRetry(retry *Retry) error {
+	if retry == nil {
+		return nil
+	}
+	if retry.MaxAttempts < 1 {
+		return fmt.Errorf("max_attempts must be at least 1")
+	}
+	if retry.Backoff < 0 || retry.MaxBackoff < 0 {
+		return fmt.Errorf("backoff must not be negative")
+	}
+	if retry.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be at least 1")
+	}
+	for _, code := range retry.ExitCodes {
+		if code < 1 || code > 255 {
+			return fmt.Errorf("invalid exit code %d", code)
+		}
+	}
+	if _, err := retry.StderrRegexps(); err != nil {
+		return fmt.Errorf("invalid stderr pattern: %w", err)
+	}
+	return nil
+}
+
+// validShells are the shells that accept a script with -c and POSIX
+// single quoting
+var validShells = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "ksh": true, "zsh": true,
+}
+
+// validateShell checks that a shell name or path is a supported shell
+func validateShell(shell string) error {
+	if !validShells[filepath.Base(shell)] {
+		return fmt.Errorf("unsupported shell %s (must be sh, bash, dash, ksh or zsh)", shell)
+	}
+	return nil
+}
+
+// validateScript checks the shell mode settings of a tool
+func validateScript(c *Config, tool *Tool) error {
+	if tool.Script == "" {
+		if tool.Shell != "" || tool.ShellArgs != "" {
+			return fmt.Errorf("shell and shell_args require a script")
 		}
+		return nil
+	}
+	if tool.Command != "" || len(tool.Chain) > 0 {
+		return fmt.Errorf("script cannot be combined with command or chain")
 	}
+	if err := validateShell(tool.ScriptShell(&c.Settings)); err != nil {
+		return err
+	}
+	switch tool.ShellArgs {
+	case "", "quote", "env":
+	default:
+		return fmt.Errorf("invalid shell_args %s (must be quote or env)", tool.ShellArgs)
+	}
+	if tool.ShellArgs != "env" {
+		isArg := func(name string) bool { return tool.Argument(name) != nil }
+		if name, where := quotedPlaceholder(tool.Script, isArg); name != "" {
+			return fmt.Errorf("placeholder ${%s} is inside %s, where its quoting does not protect it; "+
+				"use it unquoted or set shell_args: env", name, where)
+		}
+	}
+	return nil
+}
 
+// validateArgumentRendering checks the template, false_flag and join
+// options of an argument
+func validateArgumentRendering(arg Argument) error {
+	if arg.Template != "" && len(arg.Tokens) > 0 {
+		return fmt.Errorf("template and tokens cannot both be set")
+	}
+	if (arg.Template != "" || len(arg.Tokens) > 0) && arg.Flag != "" {
+		return fmt.Errorf("template and tokens replace flag; include the flag in the template")
+	}
+	for _, text := range append([]string{arg.Template}, arg.Tokens...) {
+		if err := template.Check(text); err != nil {
+			return err
+		}
+	}
+	if arg.FalseFlag != "" && arg.Type != "boolean" {
+		return fmt.Errorf("false_flag requires a boolean argument")
+	}
+	if arg.Join != "" && arg.Type != "array" && arg.Pairs == "" {
+		return fmt.Errorf("join requires an array argument or an object with pairs")
+	}
+	return nil
+}
+
+var validArgTypes = map[string]bool{
+	"string": true, "boolean": true, "integer": true,
+	"array": true, "object": true, "float": true, "path": true,
+}
+
+// validFormats are the format hints arguments may declare
+var validFormats = map[string]bool{
+	"path": true, "uri": true, "date-time": true, "date": true,
+	"email": true, "hostname": true,
+}
+
+// validateArgumentValues checks an argument's format, items, properties,
+// pairs, path settings and sanitize mode
+func validateArgumentValues(arg *Argument) error {
+	if arg.Pairs != "" && arg.Type != "object" {
+		return fmt.Errorf("pairs requires an object argument")
+	}
+	if (arg.MustExist || arg.Kind != "" || arg.Access != "") && !arg.IsPath() {
+		return fmt.Errorf("must_exist, kind and access require a path argument")
+	}
+	if arg.Kind != "" && arg.Kind != "file" && arg.Kind != "dir" {
+		return fmt.Errorf("invalid kind %s (must be file or dir)", arg.Kind)
+	}
+	if arg.Access != "" && arg.Access != "read" && arg.Access != "write" {
+		return fmt.Errorf("invalid access %s (must be read or write)", arg.Access)
+	}
+	switch arg.Sanitize {
+	case "", "none", "flag", "shell":
+	default:
+		return fmt.Errorf("invalid sanitize %s (must be none, flag or shell)", arg.Sanitize)
+	}
+	if arg.CheckPath != nil && !*arg.CheckPath && arg.IsPath() {
+		return fmt.Errorf("check_path cannot be disabled for a path argument")
+	}
+	return validateValue(&Value{
+		Type:       arg.Type,
+		Format:     arg.Format,
+		Items:      arg.Items,
+		Properties: arg.Properties,
+	})
+}
+
+// validateValue checks a value description and its items and properties
+func validateValue(value *Value) error {
+	if !validArgTypes[value.Type] {
+		return fmt.Errorf("invalid type %s", value.Type)
+	}
+	if value.Format != "" && !validFormats[value.Format] {
+		return fmt.Errorf("invalid format %s", value.Format)
+	}
+	if value.Items != nil {
+		if value.Type != "array" {
+			return fmt.Errorf("items requires an array")
+		}
+		if err := validateValue(value.Items); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+	if len(value.Properties) > 0 && value.Type != "object" {
+		return fmt.Errorf("properties requires an object")
+	}
+	for name, property := range value.Properties {
+		if property == nil {
+			return fmt.Errorf("property %s: type is required", name)
+		}
+		if err := validateValue(property); err != nil {
+			return fmt.Errorf("property %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateCondition checks that a parsed when condition only refers to the
+// tool's arguments
+func validateCondition(tool *Tool, condition *expr.Expr) error {
+	if condition == nil {
+		return nil
+	}
+	for _, name := range condition.Variables() {
+		if tool.Argument(name) == nil {
+			return fmt.Errorf("unknown argument %q in condition %q", name, condition.String())
+		}
+	}
+	return nil
+}
+
+// validateToolConditions parses a tool's when and confirm_when conditions.
+// A tool may declare the confirm argument itself, as a boolean.
+func validateToolConditions(tool *Tool) error {
+	condition, err := tool.Condition()
+	if err == nil {
+		err = validateCondition(tool, condition)
+	}
+	if err != nil {
+		return fmt.Errorf("when: %w", err)
+	}
+
+	condition, err = tool.ConfirmCondition()
+	if err == nil {
+		err = validateCondition(tool, condition)
+	}
+	if err != nil {
+		return fmt.Errorf("confirm_when: %w", err)
+	}
+	if arg := tool.Argument("confirm"); condition != nil && arg != nil && arg.Type != "boolean" {
+		return fmt.Errorf("confirm_when: argument confirm must be a boolean")
+	}
+	return nil
+}
+
+// validateConstraint checks that a constraint sets one kind and names
+// arguments of the tool
+func validateConstraint(tool *Tool, constraint Constraint) error {
+	var kinds []string
+	var names []string
+	groups := []struct {
+		kind  string
+		names []string
+	}{
+		{"exclusive", constraint.Exclusive},
+		{"one_of", constraint.OneOf},
+		{"at_least_one", constraint.AtLeastOne},
+	}
+	for _, group := range groups {
+		if len(group.names) == 0 {
+			continue
+		}
+		kinds = append(kinds, group.kind)
+		if len(group.names) < 2 {
+			return fmt.Errorf("%s needs at least two arguments", group.kind)
+		}
+		names = append(names, group.names...)
+	}
+	if len(constraint.Requires) > 0 {
+		kinds = append(kinds, "requires")
+		for name, required := range constraint.Requires {
+			if len(required) == 0 {
+				return fmt.Errorf("requires: %s lists no arguments", name)
+			}
+			names = append(names, name)
+			names = append(names, required...)
+		}
+	}
+
+	switch len(kinds) {
+	case 0:
+		return fmt.Errorf("one of exclusive, requires, one_of or at_least_one is required")
+	case 1:
+	default:
+		return fmt.Errorf("only one of exclusive, requires, one_of or at_least_one may be set")
+	}
+
+	for _, name := range names {
+		if tool.Argument(name) == nil {
+			return fmt.Errorf("unknown argument %q", name)
+		}
+	}
+	return nil
+}
+
+// validateCSV checks the CSV delimiter and comment characters
+func validateCSV(output *Output) error {
+	delimiter, err := output.DelimiterRune()
+	if err != nil {
+		return err
+	}
+	comment, err := output.CommentRune()
+	if err != nil {
+		return err
+	}
+	if comment == delimiter {
+		return fmt.Errorf("comment and delimiter must differ")
+	}
+	return nil
+}
+
+// validateErrorRules checks error rules and compiles their stderr patterns
+func validateErrorRules(rules []ErrorRule) error {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Message == "" {
+			return fmt.Errorf("rule %d: message is required", i+1)
+		}
+		if _, err := rule.StderrRegexp(); err != nil {
+			return fmt.Errorf("rule %d: invalid stderr pattern: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateTransform checks output transform steps and compiles their patterns
+func validateTransform(steps []TransformStep) error {
+	for i := range steps {
+		step := &steps[i]
+		if step.Operations() != 1 {
+			return fmt.Errorf("step %d: exactly one operation must be set", i+1)
+		}
+		if step.Head < 0 || step.Tail < 0 || step.MaxTokens < 0 {
+			return fmt.Errorf("step %d: line and token counts must be positive", i+1)
+		}
+		if _, err := step.Regexp(); err != nil {
+			return fmt.Errorf("step %d: invalid pattern: %w", i+1, err)
+		}
+	}
 	return nil
 }
\ No newline at end of file
@@ -99,6 +99,53 @@ tools:
 	assert.Equal(t, "-v", arg2.Flag)
 }
 
+func TestLoadConfigSchemaFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schemas", "status.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(schemaPath), 0755))
+	require.NoError(t, os.WriteFile(schemaPath, []byte(`{"type": "object", "required": ["ok"], "properties": {"ok": {"type": "boolean"}}}`), 0644))
+
+	configPath := filepath.Join(tmpDir, "test.yaml")
+	err := os.WriteFile(configPath, []byte(`
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: status
+    description: Show status
+    output:
+      type: json
+      schema_file: schemas/status.json
+      on_mismatch: fail
+`), 0644)
+	require.NoError(t, err)
+
+	cfg, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"type":       "object",
+		"required":   []interface{}{"ok"},
+		"properties": map[string]interface{}{"ok": map[string]interface{}{"type": "boolean"}},
+	}, cfg.Tools[0].Output.Schema)
+	assert.True(t, cfg.Tools[0].Output.PublishesSchema())
+
+	require.NoError(t, os.Remove(schemaPath))
+	_, err = LoadConfig(configPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load output schema")
+}
+
+func TestOutputPublishesSchema(t *testing.T) {
+	object := map[string]interface{}{"type": "object"}
+	assert.True(t, (&Output{Schema: object, OnMismatch: "fail"}).PublishesSchema())
+	assert.False(t, (&Output{Schema: object}).PublishesSchema())
+	assert.False(t, (&Output{Schema: object, OnMismatch: "pass"}).PublishesSchema())
+	assert.False(t, (&Output{Schema: map[string]interface{}{"type": "array"}, OnMismatch: "fail"}).PublishesSchema())
+	assert.False(t, (&Output{OnMismatch: "fail"}).PublishesSchema())
+}
+
 func TestLoadConfigValidation(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -106,44 +153,473 @@ func TestLoadConfigValidation(t *testing.T) {
 		expectError string
 	}{
 		{
-			name: "missing metadata name",
+			name: "missing metadata name",
+			config: `
+version: "1.0"
+metadata:
+  description: Test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+`,
+			expectError: "metadata.name is required",
+		},
+		{
+			name: "missing command",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+tools:
+  - name: test
+    description: Test tool
+`,
+			expectError: "settings.command is required",
+		},
+		{
+			name: "no tools",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+`,
+			expectError: "at least one tool must be defined",
+		},
+		{
+			name: "invalid output type",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      type: invalid
+`,
+			expectError: "invalid output type",
+		},
+		{
+			name: "regex without pattern",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      type: regex
+`,
+			expectError: "pattern is required for regex output",
+		},
+		{
+			name: "invalid success exit code",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    success_exit_codes: [0, 256]
+`,
+			expectError: "invalid success exit code 256",
+		},
+		{
+			name: "error rule without message",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+errors:
+  - exit_code: 1
+tools:
+  - name: test
+    description: Test tool
+`,
+			expectError: "errors: rule 1: message is required",
+		},
+		{
+			name: "error rule with invalid stderr pattern",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    errors:
+      - stderr: "([a-z"
+        message: broken
+`,
+			expectError: "tool test: errors: rule 1: invalid stderr pattern",
+		},
+		{
+			name: "transform step with two operations",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      transform:
+        - head: 10
+          dedupe: true
+`,
+			expectError: "tool test: transform: step 1: exactly one operation must be set",
+		},
+		{
+			name: "transform step with invalid pattern",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      transform:
+        - include: "(["
+`,
+			expectError: "tool test: transform: step 1: invalid pattern",
+		},
+		{
+			name: "table column with end before start",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      type: table
+      columns:
+        - name: size
+          start: 10
+          end: 4
+`,
+			expectError: "tool test: columns: column size: end must be greater than start",
+		},
+		{
+			name: "csv with multi-character delimiter",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      type: csv
+      delimiter: "::"
+`,
+			expectError: "tool test: delimiter must be a single character",
+		},
+		{
+			name: "regex with invalid pattern",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      type: regex
+      patterns:
+        - name: header
+          pattern: "(unclosed"
+`,
+			expectError: "tool test: patterns: entry 1: invalid regex pattern",
+		},
+		{
+			name: "invalid jq filter",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      type: json
+      jq: ".items[0"
+`,
+			expectError: `tool test: invalid jq filter ".items[0"`,
+		},
+		{
+			name: "invalid on_mismatch",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      type: json
+      on_mismatch: ignore
+`,
+			expectError: "tool test: invalid on_mismatch ignore",
+		},
+		{
+			name: "invalid output schema",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      type: json
+      schema:
+        type: dict
+`,
+			expectError: "tool test: output schema: #/type: invalid type dict",
+		},
+		{
+			name: "file output with unknown file_arg",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      type: file
+      file_arg: output
+`,
+			expectError: "tool test: file_arg output is not an argument of the tool",
+		},
+		{
+			name: "diff with negative max_lines",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    output:
+      type: diff
+      max_lines: -1
+`,
+			expectError: "tool test: max_hunks and max_lines must not be negative",
+		},
+		{
+			name: "cache without ttl",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    cache:
+      watch: [.git/HEAD]
+`,
+			expectError: "tool test: cache: ttl must be positive",
+		},
+		{
+			name: "cache invalidated by unknown tool",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    cache:
+      ttl: 10s
+      invalidated_by: [commit]
+`,
+			expectError: "tool test: cache: invalidated_by: unknown tool commit",
+		},
+		{
+			name: "retry with invalid stderr pattern",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    retry:
+      stderr: ["timed out ("]
+`,
+			expectError: "tool test: retry: invalid stderr pattern",
+		},
+		{
+			name: "script with unsupported shell",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  shell: python3
+tools:
+  - name: test
+    description: Test tool
+    script: ls | head
+`,
+			expectError: "settings: unsupported shell python3",
+		},
+		{
+			name: "script with command",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    command: sub
+    script: ls | head
+`,
+			expectError: "tool test: script cannot be combined with command or chain",
+		},
+		{
+			name: "shell without script",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    shell: bash
+`,
+			expectError: "tool test: shell and shell_args require a script",
+		},
+		{
+			name: "template with flag",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: days
+        type: integer
+        flag: --since
+        template: "--since={{.days}}d"
+`,
+			expectError: "tool test, argument days: template and tokens replace flag",
+		},
+		{
+			name: "invalid argument template",
 			config: `
 version: "1.0"
 metadata:
-  description: Test
+  name: test
 settings:
   command: test
 tools:
   - name: test
     description: Test tool
+    arguments:
+      - name: fields
+        type: array
+        tokens: ["--format", "{{join .fields}"]
 `,
-			expectError: "metadata.name is required",
+			expectError: "tool test, argument fields: invalid template",
 		},
 		{
-			name: "missing command",
+			name: "join on a string argument",
 			config: `
 version: "1.0"
 metadata:
   name: test
+settings:
+  command: test
 tools:
   - name: test
     description: Test tool
+    arguments:
+      - name: labels
+        type: string
+        flag: --label
+        join: ","
 `,
-			expectError: "settings.command is required",
+			expectError: "tool test, argument labels: join requires an array argument",
 		},
 		{
-			name: "no tools",
+			name: "invalid when condition",
 			config: `
 version: "1.0"
 metadata:
   name: test
 settings:
   command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: verbose
+        type: boolean
+        flag: -v
+        when: "${debug} == true &&"
+      - name: debug
+        type: boolean
 `,
-			expectError: "at least one tool must be defined",
+			expectError: `tool test, argument verbose: when: invalid condition "${debug} == true &&": expected a value, found end of condition at column 20`,
 		},
 		{
-			name: "invalid output type",
+			name: "when condition with unknown argument",
 			config: `
 version: "1.0"
 metadata:
@@ -153,13 +629,16 @@ settings:
 tools:
   - name: test
     description: Test tool
-    output:
-      type: invalid
+    arguments:
+      - name: verbose
+        type: boolean
+        flag: -v
+        when: "exists(debug)"
 `,
-			expectError: "invalid output type",
+			expectError: `tool test, argument verbose: when: unknown argument "debug" in condition "exists(debug)"`,
 		},
 		{
-			name: "regex without pattern",
+			name: "tool when condition with unknown argument",
 			config: `
 version: "1.0"
 metadata:
@@ -169,10 +648,281 @@ settings:
 tools:
   - name: test
     description: Test tool
-    output:
-      type: regex
+    when: "${branch} != main"
+    arguments:
+      - name: force
+        type: boolean
 `,
-			expectError: "pattern is required for regex output",
+			expectError: `tool test: when: unknown argument "branch" in condition "${branch} != main"`,
+		},
+		{
+			name: "confirm_when with a non-boolean confirm argument",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    confirm_when: "${force}"
+    arguments:
+      - name: force
+        type: boolean
+      - name: confirm
+        type: string
+`,
+			expectError: "tool test: confirm_when: argument confirm must be a boolean",
+		},
+		{
+			name: "constraint with unknown argument",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: ports
+        type: array
+    constraints:
+      - requires: {ports: [image]}
+`,
+			expectError: `tool test: constraint 1: unknown argument "image"`,
+		},
+		{
+			name: "constraint with two kinds",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: a
+      - name: b
+    constraints:
+      - exclusive: [a, b]
+        one_of: [a, b]
+`,
+			expectError: "tool test: constraint 1: only one of exclusive, requires, one_of or at_least_one may be set",
+		},
+		{
+			name: "constraint with one argument",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: a
+    constraints:
+      - one_of: [a]
+`,
+			expectError: "tool test: constraint 1: one_of needs at least two arguments",
+		},
+		{
+			name: "invalid item type",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: ids
+        type: array
+        items:
+          type: number
+`,
+			expectError: "tool test, argument ids: items: invalid type number",
+		},
+		{
+			name: "invalid property format",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: target
+        type: object
+        properties:
+          since:
+            format: timestamp
+`,
+			expectError: "tool test, argument target: property since: invalid format timestamp",
+		},
+		{
+			name: "pairs on an array",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: env
+        type: array
+        pairs: "="
+`,
+			expectError: "tool test, argument env: pairs requires an object argument",
+		},
+		{
+			name: "must_exist on a string argument",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: file
+        type: string
+        must_exist: true
+`,
+			expectError: "tool test, argument file: must_exist, kind and access require a path argument",
+		},
+		{
+			name: "invalid path kind",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: files
+        type: array
+        items:
+          type: path
+        kind: socket
+`,
+			expectError: "tool test, argument files: invalid kind socket (must be file or dir)",
+		},
+		{
+			name: "placeholder in double quotes",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+tools:
+  - name: test
+    description: Test tool
+    script: 'echo "msg: ${msg}"'
+    arguments:
+      - name: msg
+`,
+			expectError: "tool test: placeholder ${msg} is inside double quotes",
+		},
+		{
+			name: "invalid sanitize mode",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: message
+        sanitize: quote
+`,
+			expectError: "tool test, argument message: invalid sanitize quote (must be none, flag or shell)",
+		},
+		{
+			name: "path check disabled on a path argument",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+tools:
+  - name: test
+    description: Test tool
+    arguments:
+      - name: file
+        type: path
+        check_path: false
+`,
+			expectError: "tool test, argument file: check_path cannot be disabled for a path argument",
+		},
+		{
+			name: "unresolvable allowed command",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+security:
+  allowed_commands: [umcp-no-such-command]
+tools:
+  - name: test
+    description: Test tool
+`,
+			expectError: "security: allowed command umcp-no-such-command:",
+		},
+		{
+			name: "denied flag without a dash",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+security:
+  denied_flags: ["exec"]
+tools:
+  - name: test
+    description: Test tool
+`,
+			expectError: "security: denied flag exec: pattern must start with -",
+		},
+		{
+			name: "invalid command sha256",
+			config: `
+version: "1.0"
+metadata:
+  name: test
+settings:
+  command: test
+  command_sha256: abc
+tools:
+  - name: test
+    description: Test tool
+`,
+			expectError: "settings: command_sha256 must be 64 hex digits",
 		},
 	}
 
@@ -217,7 +967,49 @@ func TestApplyDefaults(t *testing.T) {
 	assert.Equal(t, "1.0", cfg.Version)
 	assert.Equal(t, ".", cfg.Settings.WorkingDir)
 	assert.Equal(t, 30*time.Second, cfg.Settings.Timeout)
+	assert.Equal(t, "lenient", cfg.Settings.Coercion)
 	assert.Equal(t, int64(10*1024*1024), cfg.Security.MaxOutputSize)
 	assert.Equal(t, "raw", cfg.Tools[0].Output.Type)
 	assert.Equal(t, "string", cfg.Tools[0].Arguments[0].Type)
-}
\ No newline at end of file
+}
+
+func TestApplyDefaultsRetry(t *testing.T) {
+	cfg := &Config{
+		Tools: []Tool{
+			{Name: "fetch", Retry: &Retry{MaxBackoff: 3 * time.Second}},
+		},
+	}
+
+	require.NoError(t, cfg.applyDefaults())
+	retry := cfg.Tools[0].Retry
+	assert.Equal(t, 3, retry.MaxAttempts)
+	assert.Equal(t, time.Second, retry.Backoff)
+	assert.Equal(t, 2.0, retry.Multiplier)
+
+	assert.Equal(t, time.Second, retry.Delay(1))
+	assert.Equal(t, 2*time.Second, retry.Delay(2))
+	assert.Equal(t, 3*time.Second, retry.Delay(3))
+}
+
+func TestLoadConfigScriptOnly(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "test.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+version: "1.0"
+metadata:
+  name: test
+settings:
+  shell: /bin/bash
+tools:
+  - name: go_files
+    description: List Go files
+    script: ls *.go | head -n ${limit}
+    arguments:
+      - name: limit
+        type: integer
+        default: 10
+`), 0644))
+
+	cfg, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "/bin/bash", cfg.Tools[0].ScriptShell(&cfg.Settings))
+}
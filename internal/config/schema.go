@@ -1,6 +1,14 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"time"
+
+	"github.com/charignon/umcp/internal/expr"
+	"github.com/charignon/umcp/internal/jq"
+)
 
 // Config represents the complete YAML configuration for a CLI tool
 type Config struct {
@@ -9,6 +17,8 @@ type Config struct {
 	Settings Settings  `yaml:"settings"`
 	Security Security  `yaml:"security"`
 	Tools    []Tool    `yaml:"tools"`
+
+	Errors []ErrorRule `yaml:"errors"` // Error rules shared by all tools
 }
 
 // Metadata contains information about the tool
@@ -24,7 +34,14 @@ type Settings struct {
 	WorkingDir  string            `yaml:"working_dir"`
 	Timeout     time.Duration     `yaml:"timeout"`
 	Environment []string          `yaml:"environment"`
-	Shell       string            `yaml:"shell"`
+	Shell       string            `yaml:"shell"` // Shell that runs tool scripts (default sh)
+
+	CallTimeout time.Duration `yaml:"call_timeout"` // Deadline for a whole tool call, retries included (default 3 × timeout)
+	Coercion    string        `yaml:"coercion"`     // How argument values are converted: lenient (default) or strict
+
+	CommandSHA256 string `yaml:"command_sha256"` // Expected SHA-256 of the command binary, checked at startup
+
+	pin *commandPin // Set by Preflight
 }
 
 // Security contains security settings
@@ -34,6 +51,13 @@ type Security struct {
 	MaxOutputSize    int64    `yaml:"max_output_size"`
 	RateLimit        string   `yaml:"rate_limit"`
 	DisableInjectionCheck bool `yaml:"disable_injection_check"` // Allow disabling injection detection for trusted tools
+
+	AllowedCommands    []string `yaml:"allowed_commands"`    // Binaries that may run, resolved through PATH at load time
+	AllowedSubcommands []string `yaml:"allowed_subcommands"` // Subcommands that may follow the command
+	DeniedFlags        []string `yaml:"denied_flags"`        // Glob patterns of flags that may not appear, e.g. --exec or -c*
+
+	allowedCommands []string         // AllowedCommands as absolute paths with symlinks evaluated
+	deniedFlags     []*regexp.Regexp // Compiled DeniedFlags
 }
 
 // Tool represents a single MCP tool that wraps a CLI command
@@ -44,6 +68,103 @@ type Tool struct {
 	Arguments   []Argument `yaml:"arguments"`
 	Output      Output     `yaml:"output"`
 	Chain       []Chain    `yaml:"chain"`
+
+	SuccessExitCodes []int        `yaml:"success_exit_codes"` // Exit codes treated as success (default: 0)
+	Errors           []ErrorRule  `yaml:"errors"`             // Checked before the config-level rules
+	Cache            *Cache       `yaml:"cache"`              // Reuse results of identical calls
+	Retry            *Retry       `yaml:"retry"`              // Rerun the command after transient failures
+	Constraints      []Constraint `yaml:"constraints"`        // Rules about which arguments go together
+
+	// Script runs the tool through a shell instead of settings.command.
+	// ${name} placeholders are replaced by single-quoted argument words, or
+	// with ShellArgs "env" by references to UMCP_ARG_* variables.
+	Script    string `yaml:"script"`
+	Shell     string `yaml:"shell"`      // Overrides settings.shell
+	ShellArgs string `yaml:"shell_args"` // quote (default) or env
+
+	// When lets the tool run only for calls where the condition holds.
+	// ConfirmWhen makes calls where it holds fail until they are repeated
+	// with confirm: true. Both use the argument condition language.
+	When        string `yaml:"when"`
+	ConfirmWhen string `yaml:"confirm_when"`
+
+	condition        *expr.Expr
+	confirmCondition *expr.Expr
+}
+
+// ScriptShell returns the shell that runs the tool's script, or "" when the
+// tool has no script
+func (t *Tool) ScriptShell(settings *Settings) string {
+	switch {
+	case t.Script == "":
+		return ""
+	case t.Shell != "":
+		return t.Shell
+	case settings.Shell != "":
+		return settings.Shell
+	default:
+		return "sh"
+	}
+}
+
+// Condition returns the parsed When condition, or nil if none is set.
+// Conditions are parsed once at load time.
+func (t *Tool) Condition() (*expr.Expr, error) {
+	if t.When == "" {
+		return nil, nil
+	}
+	if t.condition == nil {
+		condition, err := expr.Parse(t.When)
+		if err != nil {
+			return nil, err
+		}
+		t.condition = condition
+	}
+	return t.condition, nil
+}
+
+// ConfirmCondition returns the parsed ConfirmWhen condition, or nil if none
+// is set
+func (t *Tool) ConfirmCondition() (*expr.Expr, error) {
+	if t.ConfirmWhen == "" {
+		return nil, nil
+	}
+	if t.confirmCondition == nil {
+		condition, err := expr.Parse(t.ConfirmWhen)
+		if err != nil {
+			return nil, err
+		}
+		t.confirmCondition = condition
+	}
+	return t.confirmCondition, nil
+}
+
+// Argument returns the tool's argument with the given name, or nil
+func (t *Tool) Argument(name string) *Argument {
+	for i := range t.Arguments {
+		if t.Arguments[i].Name == name {
+			return &t.Arguments[i]
+		}
+	}
+	return nil
+}
+
+// Cache configures result caching for a read-only tool. Results are keyed
+// by the normalized arguments and the working directory.
+type Cache struct {
+	TTL           time.Duration `yaml:"ttl"`
+	Watch         []string      `yaml:"watch"`          // Files or globs whose modification invalidates the result
+	InvalidatedBy []string      `yaml:"invalidated_by"` // Tools whose calls clear this tool's results
+}
+
+// Constraint relates the arguments of a tool. Each constraint sets exactly
+// one kind. An argument counts as given when the caller passes a value
+// other than null, false, "" or an empty list; defaults do not count.
+type Constraint struct {
+	Exclusive  []string            `yaml:"exclusive"`    // At most one may be given
+	Requires   map[string][]string `yaml:"requires"`     // Each key needs all of the listed arguments
+	OneOf      []string            `yaml:"one_of"`       // Exactly one must be given
+	AtLeastOne []string            `yaml:"at_least_one"` // One or more must be given
 }
 
 // Argument represents a command-line argument
@@ -57,9 +178,116 @@ type Argument struct {
 	Min          *int        `yaml:"min"`
 	Max          *int        `yaml:"max"`
 	Validation   string      `yaml:"validation"`
-	When         string      `yaml:"when"`
+	When         string      `yaml:"when"` // Condition in the internal/expr language
 	Positional   bool        `yaml:"positional"`
 	Position     int         `yaml:"position"`
+
+	Sensitive bool `yaml:"sensitive"` // Redact the value in audit logs and traces
+
+	// Template renders the argument as one token and Tokens as several,
+	// replacing flag and value. Both use the template language of the
+	// internal/template package; empty tokens are dropped.
+	Template  string   `yaml:"template"`
+	Tokens    []string `yaml:"tokens"`
+	FalseFlag string   `yaml:"false_flag"` // Emitted when a boolean is false, e.g. --no-color
+	Join      string   `yaml:"join"`       // Pass an array as one value joined by this separator
+
+	// Format is a JSON Schema format hint such as path, uri or date-time.
+	// Items describes array items and Properties object properties; Pairs
+	// renders an object as one KEY<Pairs>VALUE item per entry, like an array.
+	Format     string            `yaml:"format"`
+	Items      *Value            `yaml:"items"`
+	Properties map[string]*Value `yaml:"properties"`
+	Pairs      string            `yaml:"pairs"`
+
+	// Path arguments (type path, or arrays of path items) are resolved
+	// against the working directory with symlinks evaluated, and must lie
+	// within security.allowed_paths.
+	MustExist bool   `yaml:"must_exist"`
+	Kind      string `yaml:"kind"`   // file or dir
+	Access    string `yaml:"access"` // read or write
+
+	// Sanitize is how the caller's values are checked before the command
+	// runs: none, flag (must not start with -) or shell (flag, and no shell
+	// metacharacters). CheckPath false skips the allowed_paths check of
+	// values that look like paths; path arguments are always checked.
+	Sanitize  string `yaml:"sanitize"`
+	CheckPath *bool  `yaml:"check_path"`
+
+	condition *expr.Expr
+}
+
+// Value describes an array item or object property
+type Value struct {
+	Type        string            `yaml:"type"`
+	Description string            `yaml:"description"`
+	Format      string            `yaml:"format"`
+	Min         *int              `yaml:"min"`
+	Max         *int              `yaml:"max"`
+	Required    bool              `yaml:"required"` // Object properties only
+	Items       *Value            `yaml:"items"`
+	Properties  map[string]*Value `yaml:"properties"`
+}
+
+// SensitiveName matches argument names whose values are redacted in audit
+// logs and traces even when the config does not mark them as sensitive
+var SensitiveName = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|credential|private_?key)`)
+
+// IsSensitive reports whether values of the named argument are redacted in
+// audit logs and traces: the argument is marked sensitive or its name looks
+// like a credential
+func (t *Tool) IsSensitive(name string) bool {
+	if arg := t.Argument(name); arg != nil && arg.Sensitive {
+		return true
+	}
+	return SensitiveName.MatchString(name)
+}
+
+// IsPath reports whether an argument holds a path or a list of paths
+func (a *Argument) IsPath() bool {
+	return a.Type == "path" || (a.Type == "array" && a.ItemType() == "path")
+}
+
+// ItemType returns the type of an array argument's items
+func (a *Argument) ItemType() string {
+	if a.Items == nil {
+		return "string"
+	}
+	return a.Items.Type
+}
+
+// SanitizeMode returns how the argument's values are checked. It defaults
+// to shell, or none when the config disables the injection check.
+func (a *Argument) SanitizeMode(security *Security) string {
+	if a.Sanitize != "" {
+		return a.Sanitize
+	}
+	if security.DisableInjectionCheck {
+		return "none"
+	}
+	return "shell"
+}
+
+// ChecksPaths reports whether values that look like paths are checked
+// against allowed_paths
+func (a *Argument) ChecksPaths() bool {
+	return a.IsPath() || a.CheckPath == nil || *a.CheckPath
+}
+
+// Condition returns the parsed When condition, or nil if none is set.
+// Conditions are parsed once at load time.
+func (a *Argument) Condition() (*expr.Expr, error) {
+	if a.When == "" {
+		return nil, nil
+	}
+	if a.condition == nil {
+		condition, err := expr.Parse(a.When)
+		if err != nil {
+			return nil, err
+		}
+		a.condition = condition
+	}
+	return a.condition, nil
 }
 
 // Output defines how to parse command output
@@ -68,12 +296,291 @@ type Output struct {
 	Pattern string       `yaml:"pattern"`
 	Groups  []Group      `yaml:"groups"`
 	JQ      string       `yaml:"jq"`
+
+	Patterns  []RegexPattern  `yaml:"patterns"`  // Several named regex patterns
+	Single    bool            `yaml:"single"`    // regex: return the first match instead of an array
+	Separator string          `yaml:"separator"` // keyvalue separator; defaults to the first "=" or ":"
+	Columns   []Group         `yaml:"columns"`   // table and csv column specs or type hints
+	Select    string          `yaml:"select"`    // XPath-like selector for xml output
+	Transform []TransformStep `yaml:"transform"` // Post-processing applied around parsing
+
+	// CSV options
+	Delimiter  string `yaml:"delimiter"`   // Field delimiter; "tab" or "\t" for tabs (default ",")
+	HasHeader  *bool  `yaml:"has_header"`  // First row holds column names (default true)
+	Comment    string `yaml:"comment"`     // Lines starting with this character are skipped
+	LazyQuotes bool   `yaml:"lazy_quotes"` // Allow bare and unescaped quotes in fields
+
+	// Diff options
+	StatOnly bool `yaml:"stat_only"` // Return file metadata and counts without hunks
+	MaxHunks int  `yaml:"max_hunks"` // Hunks kept per file; 0 keeps all
+	MaxLines int  `yaml:"max_lines"` // Hunk lines kept per file; 0 keeps all
+
+	// Binary output (image, file, blob) is read from File, from the path in
+	// the FileArg argument, or from stdout. When the caller omits FileArg a
+	// temp file named after File is created and removed afterwards.
+	File     string `yaml:"file"`
+	FileArg  string `yaml:"file_arg"`
+	MimeType string `yaml:"mime_type"` // Overrides MIME type detection
+
+	// Schema validates the parsed output; SchemaFile loads it from a JSON
+	// or YAML file relative to the config file
+	Schema     map[string]interface{} `yaml:"schema"`
+	SchemaFile string                 `yaml:"schema_file"`
+	OnMismatch string                 `yaml:"on_mismatch"` // fail, warn (default) or pass
+
+	// RecordSeparator splits regex output into multi-line records; each
+	// match starts a new record
+	RecordSeparator string `yaml:"record_separator"`
+
+	re       *regexp.Regexp
+	recordRe *regexp.Regexp
+	jq       *jq.Filter
+}
+
+// Regexp returns the compiled regex pattern, or nil if none is set.
+// Patterns are compiled once at load time.
+func (o *Output) Regexp() (*regexp.Regexp, error) {
+	if o.Pattern == "" {
+		return nil, nil
+	}
+	if o.re == nil {
+		re, err := regexp.Compile(o.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		o.re = re
+	}
+	return o.re, nil
+}
+
+// PublishesSchema reports whether the output schema is published as the
+// tool's MCP outputSchema. A tool with an outputSchema must return
+// conforming structured content, which is an object, from every successful
+// call; only an object schema with on_mismatch fail guarantees that.
+func (o *Output) PublishesSchema() bool {
+	return o.Schema != nil && o.Schema["type"] == "object" && o.OnMismatch == "fail"
+}
+
+// JQFilter returns the parsed jq filter, or nil if none is set. Filters
+// are parsed once at load time.
+func (o *Output) JQFilter() (*jq.Filter, error) {
+	if o.JQ == "" {
+		return nil, nil
+	}
+	if o.jq == nil {
+		filter, err := jq.Parse(o.JQ)
+		if err != nil {
+			return nil, err
+		}
+		o.jq = filter
+	}
+	return o.jq, nil
+}
+
+// RecordSeparatorRegexp returns the compiled record separator, or nil if
+// none is set
+func (o *Output) RecordSeparatorRegexp() (*regexp.Regexp, error) {
+	if o.RecordSeparator == "" {
+		return nil, nil
+	}
+	if o.recordRe == nil {
+		re, err := regexp.Compile(o.RecordSeparator)
+		if err != nil {
+			return nil, err
+		}
+		o.recordRe = re
+	}
+	return o.recordRe, nil
 }
 
-// Group represents a regex capture group
+// RegexPattern is one of several patterns applied to regex output
+type RegexPattern struct {
+	Name    string  `yaml:"name"`
+	Pattern string  `yaml:"pattern"`
+	Groups  []Group `yaml:"groups"`
+	Single  bool    `yaml:"single"` // Return the first match instead of an array
+
+	re *regexp.Regexp
+}
+
+// Regexp returns the compiled pattern
+func (p *RegexPattern) Regexp() (*regexp.Regexp, error) {
+	if p.re == nil {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		p.re = re
+	}
+	return p.re, nil
+}
+
+// IsBinary reports whether the output is returned as base64 content rather
+// than text
+func (o *Output) IsBinary() bool {
+	return o.Type == "image" || o.Type == "file" || o.Type == "blob"
+}
+
+// DelimiterRune returns the CSV field delimiter
+func (o *Output) DelimiterRune() (rune, error) {
+	switch o.Delimiter {
+	case "":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	return singleRune("delimiter", o.Delimiter)
+}
+
+// CommentRune returns the CSV comment character, or 0 if none is set
+func (o *Output) CommentRune() (rune, error) {
+	if o.Comment == "" {
+		return 0, nil
+	}
+	return singleRune("comment", o.Comment)
+}
+
+// Header reports whether CSV output starts with a header row
+func (o *Output) Header() bool {
+	return o.HasHeader == nil || *o.HasHeader
+}
+
+// singleRune returns the only character of value
+func singleRune(field, value string) (rune, error) {
+	runes := []rune(value)
+	if len(runes) != 1 {
+		return 0, fmt.Errorf("%s must be a single character, got %q", field, value)
+	}
+	if runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' {
+		return 0, fmt.Errorf("%s cannot be %q", field, value)
+	}
+	return runes[0], nil
+}
+
+// TransformStep is one step of the output post-processing pipeline. Each
+// step sets exactly one operation. Line steps run in order on stdout before
+// parsing; max_tokens truncates the final result.
+type TransformStep struct {
+	StripANSI bool   `yaml:"strip_ansi"`
+	Head      int    `yaml:"head"`    // Keep the first N lines
+	Tail      int    `yaml:"tail"`    // Keep the last N lines
+	Include   string `yaml:"include"` // Keep lines matching this regex
+	Exclude   string `yaml:"exclude"` // Drop lines matching this regex
+	Dedupe    bool   `yaml:"dedupe"`  // Drop repeated lines
+	MaxTokens int    `yaml:"max_tokens"`
+
+	re *regexp.Regexp
+}
+
+// Regexp returns the compiled include or exclude pattern, or nil if the
+// step has none
+func (t *TransformStep) Regexp() (*regexp.Regexp, error) {
+	pattern := t.Include
+	if pattern == "" {
+		pattern = t.Exclude
+	}
+	if pattern == "" {
+		return nil, nil
+	}
+	if t.re == nil {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		t.re = re
+	}
+	return t.re, nil
+}
+
+// Operations returns the number of operations set on the step
+func (t *TransformStep) Operations() int {
+	count := 0
+	for _, set := range []bool{t.StripANSI, t.Head != 0, t.Tail != 0, t.Include != "", t.Exclude != "", t.Dedupe, t.MaxTokens != 0} {
+		if set {
+			count++
+		}
+	}
+	return count
+}
+
+// Group represents a regex capture group or a table column
 type Group struct {
 	Name string `yaml:"name"`
 	Type string `yaml:"type"`
+
+	Start int `yaml:"start"` // Table column start offset (0-based)
+	End   int `yaml:"end"`   // Table column end offset (exclusive); 0 means end of line
+}
+
+// ErrorRule maps a failed command to a friendly error message. A rule
+// matches when all of its conditions match; a rule without conditions
+// matches every failure.
+type ErrorRule struct {
+	ExitCode  *int   `yaml:"exit_code"`
+	Stderr    string `yaml:"stderr"`  // Regex matched against stderr
+	Message   string `yaml:"message"` // Supports ${exit_code}, ${stderr}, named stderr groups and argument names
+	Hint      string `yaml:"hint"`
+	Retryable bool   `yaml:"retryable"`
+
+	stderrRe *regexp.Regexp
+}
+
+// StderrRegexp returns the compiled stderr pattern, or nil if the rule has
+// none. Patterns are compiled once at load time.
+func (r *ErrorRule) StderrRegexp() (*regexp.Regexp, error) {
+	if r.Stderr == "" {
+		return nil, nil
+	}
+	if r.stderrRe == nil {
+		re, err := regexp.Compile(r.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		r.stderrRe = re
+	}
+	return r.stderrRe, nil
+}
+
+// Retry configures how failed runs of a tool are retried. A run that exits
+// with a failure code is retried when the code is in ExitCodes or stderr
+// matches one of the Stderr patterns; with neither set, every failure is
+// retried. Timeouts are retried only with OnTimeout.
+type Retry struct {
+	MaxAttempts int           `yaml:"max_attempts"` // Total runs including the first (default 3)
+	Backoff     time.Duration `yaml:"backoff"`      // Delay before the second run (default 1s)
+	MaxBackoff  time.Duration `yaml:"max_backoff"`  // Upper bound on the delay
+	Multiplier  float64       `yaml:"multiplier"`   // Delay growth per attempt (default 2)
+	ExitCodes   []int         `yaml:"exit_codes"`
+	Stderr      []string      `yaml:"stderr"` // Regexes matched against stderr
+	OnTimeout   bool          `yaml:"on_timeout"`
+
+	stderrRes []*regexp.Regexp
+}
+
+// StderrRegexps returns the compiled stderr patterns. Patterns are
+// compiled once at load time.
+func (r *Retry) StderrRegexps() ([]*regexp.Regexp, error) {
+	if r.stderrRes == nil && len(r.Stderr) > 0 {
+		res := make([]*regexp.Regexp, len(r.Stderr))
+		for i, pattern := range r.Stderr {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = re
+		}
+		r.stderrRes = res
+	}
+	return r.stderrRes, nil
+}
+
+// Delay returns how long to wait after the given failed attempt (1-based)
+func (r *Retry) Delay(attempt int) time.Duration {
+	delay := float64(r.Backoff) * math.Pow(r.Multiplier, float64(attempt-1))
+	if r.MaxBackoff > 0 && delay > float64(r.MaxBackoff) {
+		return r.MaxBackoff
+	}
+	return time.Duration(delay)
 }
 
 // Chain represents a command in a command chain
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// scriptPlaceholder matches ${name} placeholders in tool scripts
+var scriptPlaceholder = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// scriptContext is where the shell would read a part of a script
+type scriptContext struct {
+	kind  string // "", "single quotes", "double quotes" or "backticks"
+	depth int    // Open parentheses inside a $( ) substitution
+	sub   bool   // Opened by $(
+}
+
+// quotedPlaceholder returns the first placeholder naming an argument that
+// sits inside quotes, backticks or a heredoc, and where it sits. With
+// shell_args quote, values are single-quoted words, which only protect them
+// where the shell reads words: single quotes are plain characters inside
+// double quotes, backticks and heredocs, and end a surrounding
+// single-quoted string. It follows the shell's quoting rules closely
+// enough for scripts in configs; it is not a shell parser.
+func quotedPlaceholder(script string, isArg func(string) bool) (string, string) {
+	stack := []scriptContext{{}}
+	var heredocs []string
+
+	for i := 0; i < len(script); i++ {
+		top := &stack[len(stack)-1]
+		c := script[i]
+
+		if strings.HasPrefix(script[i:], "${") {
+			if loc := scriptPlaceholder.FindStringSubmatchIndex(script[i:]); loc != nil && loc[0] == 0 {
+				if name := script[i+loc[2] : i+loc[3]]; top.kind != "" && isArg(name) {
+					return name, top.kind
+				}
+				i += loc[1] - 1
+				continue
+			}
+		}
+
+		switch top.kind {
+		case "single quotes":
+			if c == '\'' {
+				stack = stack[:len(stack)-1]
+			}
+		case "backticks":
+			if c == '\\' {
+				i++
+			} else if c == '`' {
+				stack = stack[:len(stack)-1]
+			}
+		case "double quotes":
+			switch {
+			case c == '\\':
+				i++
+			case c == '"':
+				stack = stack[:len(stack)-1]
+			case c == '`':
+				stack = append(stack, scriptContext{kind: "backticks"})
+			case strings.HasPrefix(script[i:], "$("):
+				stack = append(stack, scriptContext{sub: true})
+				i++
+			}
+		default:
+			switch {
+			case c == '\\':
+				i++
+			case c == '\'':
+				stack = append(stack, scriptContext{kind: "single quotes"})
+			case c == '"':
+				stack = append(stack, scriptContext{kind: "double quotes"})
+			case c == '`':
+				stack = append(stack, scriptContext{kind: "backticks"})
+			case strings.HasPrefix(script[i:], "$("):
+				stack = append(stack, scriptContext{sub: true})
+				i++
+			case c == '(' && top.sub:
+				top.depth++
+			case c == ')' && top.sub:
+				if top.depth--; top.depth < 0 {
+					stack = stack[:len(stack)-1]
+				}
+			case c == '#' && (i == 0 || strings.ContainsRune(" \t\n;&|(", rune(script[i-1]))):
+				// A comment runs to the end of the line
+				for i+1 < len(script) && script[i+1] != '\n' {
+					i++
+				}
+			case strings.HasPrefix(script[i:], "<<<"):
+				i += 2
+			case strings.HasPrefix(script[i:], "<<"):
+				var delimiter string
+				delimiter, i = heredocDelimiter(script, i+2)
+				heredocs = append(heredocs, delimiter)
+			case c == '\n' && len(heredocs) > 0:
+				for _, delimiter := range heredocs {
+					var name string
+					if name, i = heredocBody(script, i+1, delimiter, isArg); name != "" {
+						return name, "a heredoc"
+					}
+				}
+				heredocs = nil
+			}
+		}
+	}
+	return "", ""
+}
+
+// heredocDelimiter reads the delimiter after << and returns it with its
+// quotes removed, and the index of its last character
+func heredocDelimiter(script string, i int) (string, int) {
+	if i < len(script) && script[i] == '-' {
+		i++
+	}
+	for i < len(script) && (script[i] == ' ' || script[i] == '\t') {
+		i++
+	}
+	start := i
+	for i < len(script) && !strings.ContainsRune(" \t\n;&|<>()", rune(script[i])) {
+		i++
+	}
+	return strings.Trim(script[start:i], `'"\`), i - 1
+}
+
+// heredocBody scans a heredoc body starting at index i for placeholders
+// naming arguments, and returns the first one found and the index of the
+// newline ending the delimiter line
+func heredocBody(script string, i int, delimiter string, isArg func(string) bool) (string, int) {
+	for i < len(script) {
+		end := strings.IndexByte(script[i:], '\n')
+		if end < 0 {
+			end = len(script) - i
+		}
+		line := script[i : i+end]
+		if strings.TrimLeft(line, "\t") == delimiter {
+			return "", i + end
+		}
+		for _, m := range scriptPlaceholder.FindAllStringSubmatch(line, -1) {
+			if isArg(m[1]) {
+				return m[1], i + end
+			}
+		}
+		i += end + 1
+	}
+	return "", len(script)
+}
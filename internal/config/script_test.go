@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotedPlaceholder(t *testing.T) {
+	isArg := func(name string) bool { return name == "msg" || name == "file" }
+
+	tests := []struct {
+		name   string
+		script string
+		arg    string
+		where  string
+	}{
+		{name: "unquoted", script: `echo ${msg} | grep -c ${file}`},
+		{name: "shell variable in quotes", script: `echo "${HOME}" '${PATH}'`},
+		{name: "substitution in double quotes", script: `echo "lines: $(wc -l < ${file})"`},
+		{name: "nested parentheses", script: `echo $( (cat ${file}) ) ${msg}`},
+		{name: "escaped quote", script: `echo \" ${msg} \"`},
+		{name: "comment with a quote", script: "# don't worry\necho ${msg}"},
+		{name: "here-string", script: `grep x <<< ${msg}`},
+		{name: "heredoc without placeholders", script: "cat <<EOF | grep ${msg}\nhello\nEOF\necho ${file}"},
+		{name: "double quotes", script: `echo "msg: ${msg}"`, arg: "msg", where: "double quotes"},
+		{name: "single quotes", script: `echo '${file}'`, arg: "file", where: "single quotes"},
+		{name: "backticks", script: "echo `cat ${file}`", arg: "file", where: "backticks"},
+		{name: "backticks in double quotes", script: "echo \"`cat ${file}`\"", arg: "file", where: "backticks"},
+		{name: "heredoc", script: "cat <<-'EOF'\n\tmsg: ${msg}\n\tEOF\n", arg: "msg", where: "a heredoc"},
+		{name: "after a heredoc", script: "cat <<EOF\nhi\nEOF\necho \"${msg}\"", arg: "msg", where: "double quotes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arg, where := quotedPlaceholder(tt.script, isArg)
+			assert.Equal(t, tt.arg, arg)
+			assert.Equal(t, tt.where, where)
+		})
+	}
+}
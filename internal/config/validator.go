@@ -27,7 +27,10 @@ func ValidateDirectory(path string) error {
 	return nil
 }
 
-// IsPathAllowed checks if a path is within allowed paths
+// IsPathAllowed checks if a path is within allowed paths. A path is within
+// an allowed path when it is that directory or below it, so /tmp allows
+// /tmp/x but not /tmpevil. Allowed paths are compared both as written and
+// with symlinks evaluated.
 func IsPathAllowed(path string, allowedPaths []string) bool {
 	if len(allowedPaths) == 0 {
 		return true // No restrictions
@@ -43,13 +46,28 @@ func IsPathAllowed(path string, allowedPaths []string) bool {
 		if err != nil {
 			continue
 		}
-		if strings.HasPrefix(absPath, absAllowed) {
+		if isWithin(absPath, absAllowed) {
+			return true
+		}
+		if resolved, err := filepath.EvalSymlinks(absAllowed); err == nil && isWithin(absPath, resolved) {
 			return true
 		}
 	}
 	return false
 }
 
+// isWithin reports whether path is dir or inside it; both must be clean
+// absolute paths
+func isWithin(path, dir string) bool {
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
+
 // IsCommandBlocked checks if a command is in the blocked list
 func IsCommandBlocked(command string, blockedCommands []string) bool {
 	for _, blocked := range blockedCommands {
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPathAllowed(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "safe")
+	link := filepath.Join(dir, "link")
+	require.NoError(t, os.Mkdir(allowed, 0755))
+	require.NoError(t, os.Symlink(allowed, link))
+
+	assert.True(t, IsPathAllowed(allowed, []string{allowed}))
+	assert.True(t, IsPathAllowed(filepath.Join(allowed, "a", "b"), []string{allowed}))
+	assert.False(t, IsPathAllowed(allowed+"evil", []string{allowed}))
+	assert.False(t, IsPathAllowed(dir, []string{allowed}))
+	assert.True(t, IsPathAllowed("/anything", nil))
+	assert.True(t, IsPathAllowed("/etc/passwd", []string{"/"}))
+
+	// Allowed paths given through a symlink match resolved paths
+	assert.True(t, IsPathAllowed(filepath.Join(allowed, "x"), []string{link}))
+}
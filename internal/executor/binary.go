@@ -0,0 +1,145 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charignon/umcp/internal/config"
+)
+
+// Binary is image, file or blob output returned as base64 content
+type Binary struct {
+	Data     []byte
+	MimeType string
+	URI      string // Where the content came from
+}
+
+// outputFile is the file a tool writes its binary output to
+type outputFile struct {
+	path    string
+	managed bool // A temp file created and removed by the executor
+}
+
+// prepareOutputFile works out which file holds a tool's binary output. When
+// the tool names the file with file_arg and the caller omits it, a temp
+// file is created and its path is passed as that argument. The returned
+// cleanup function removes the temp file.
+func prepareOutputFile(tool *config.Tool, args map[string]interface{}) (map[string]interface{}, *outputFile, func(), error) {
+	output := &tool.Output
+	noop := func() {}
+
+	if output.FileArg != "" {
+		if path, ok := args[output.FileArg].(string); ok && path != "" {
+			return args, &outputFile{path: path}, noop, nil
+		}
+
+		dir, err := os.MkdirTemp("", "umcp-output-")
+		if err != nil {
+			return nil, nil, noop, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		name := "output"
+		if output.File != "" {
+			name = filepath.Base(output.File)
+		}
+		path := filepath.Join(dir, name)
+
+		withFile := make(map[string]interface{}, len(args)+1)
+		for k, v := range args {
+			withFile[k] = v
+		}
+		withFile[output.FileArg] = path
+		return withFile, &outputFile{path: path, managed: true}, func() { os.RemoveAll(dir) }, nil
+	}
+
+	if output.File != "" {
+		return args, &outputFile{path: output.File}, noop, nil
+	}
+	return args, nil, noop, nil
+}
+
+// readBinaryOutput reads binary output from stdout or the output file and
+// detects its MIME type. Output larger than maxSize is rejected rather
+// than truncated.
+func readBinaryOutput(tool *config.Tool, stdout string, file *outputFile, workingDir string, started time.Time, maxSize int64) (*Binary, error) {
+	binary := &Binary{URI: fmt.Sprintf("umcp://%s/output", tool.Name)}
+	name := ""
+
+	if file == nil {
+		if maxSize > 0 && int64(len(stdout)) > maxSize {
+			return nil, fmt.Errorf("output is %d bytes, larger than max_output_size (%d bytes)", len(stdout), maxSize)
+		}
+		binary.Data = []byte(stdout)
+	} else {
+		path := file.path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		name = path
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("output file was not written: %w", err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("output file %s is a directory", path)
+		}
+		// Filesystems may store modification times with one second precision
+		if info.ModTime().Before(started.Truncate(time.Second)) {
+			return nil, fmt.Errorf("output file %s was not updated by the command", path)
+		}
+		if maxSize > 0 && info.Size() > maxSize {
+			return nil, fmt.Errorf("output file is %d bytes, larger than max_output_size (%d bytes)", info.Size(), maxSize)
+		}
+
+		binary.Data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read output file: %w", err)
+		}
+		if file.managed {
+			binary.URI = fmt.Sprintf("umcp://%s/%s", tool.Name, filepath.Base(path))
+		} else {
+			binary.URI = "file://" + filepath.ToSlash(path)
+		}
+	}
+
+	binary.MimeType = tool.Output.MimeType
+	if binary.MimeType == "" {
+		binary.MimeType = detectMimeType(binary.Data, name)
+	}
+	if tool.Output.Type == "image" && !strings.HasPrefix(binary.MimeType, "image/") {
+		return nil, fmt.Errorf("expected image output, got %s", binary.MimeType)
+	}
+	return binary, nil
+}
+
+// detectMimeType sniffs the content type of data, falling back to the file
+// extension when the content looks like generic text or bytes
+func detectMimeType(data []byte, name string) string {
+	mimeType := http.DetectContentType(data)
+	generic := mimeType == "application/octet-stream" ||
+		strings.HasPrefix(mimeType, "text/plain") ||
+		strings.HasPrefix(mimeType, "text/xml")
+	if !generic {
+		return mimeType
+	}
+
+	if ext := filepath.Ext(name); ext != "" {
+		if byExt := mime.TypeByExtension(ext); byExt != "" {
+			return byExt
+		}
+	}
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	if bytes.Contains(head, []byte("<svg")) {
+		return "image/svg+xml"
+	}
+	return mimeType
+}
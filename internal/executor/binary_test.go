@@ -0,0 +1,143 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is the start of a PNG file, enough for MIME sniffing
+const pngHeader = `\211PNG\r\n\032\n\0\0\0\rIHDR`
+
+func TestExecuteImageFromStdout(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "image"})
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `printf '` + pngHeader + `'`,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result.Binary)
+	assert.Equal(t, "image/png", result.Binary.MimeType)
+	assert.Equal(t, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), result.Binary.Data)
+	assert.Equal(t, "image output (image/png, 16 bytes)", result.Output)
+
+	_, err = executor.Execute(cfg, tool, map[string]interface{}{"script": `echo hello`})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected image output, got text/plain")
+}
+
+func TestExecuteBlobSizeLimit(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "blob"})
+	cfg.Security.MaxOutputSize = 8
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{"script": `printf '1234'`})
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", result.Binary.MimeType)
+
+	_, err = executor.Execute(cfg, tool, map[string]interface{}{"script": `printf '0123456789'`})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "larger than max_output_size (8 bytes)")
+}
+
+func TestExecuteManagedOutputFile(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "file", File: "diagram.svg", FileArg: "out"})
+	tool.Arguments = append(tool.Arguments,
+		config.Argument{Name: "shell", Type: "string", Positional: true, Default: "sh"},
+		config.Argument{Name: "out", Type: "string", Positional: true},
+	)
+	executor := NewCommandExecutor()
+
+	// The script records the temp path so the test can check it is removed
+	record := filepath.Join(t.TempDir(), "path")
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `echo "$1" > ` + record + `; printf '<svg xmlns="http://www.w3.org/2000/svg"/>' > "$1"`,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "image/svg+xml", result.Binary.MimeType)
+	assert.Equal(t, "umcp://script/diagram.svg", result.Binary.URI)
+
+	written, err := os.ReadFile(record)
+	require.NoError(t, err)
+	tempPath := strings.TrimSpace(string(written))
+	assert.Equal(t, "diagram.svg", filepath.Base(tempPath))
+	_, err = os.Stat(tempPath)
+	assert.True(t, os.IsNotExist(err), "temp file should be removed")
+}
+
+func TestExecuteManagedOutputFileAllowedPaths(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "file", File: "diagram.svg", FileArg: "out"})
+	cfg.Security.AllowedPaths = []string{t.TempDir()}
+	checkPath := false
+	tool.Arguments[0].CheckPath = &checkPath
+	tool.Arguments = append(tool.Arguments,
+		config.Argument{Name: "shell", Type: "string", Positional: true, Default: "sh"},
+		config.Argument{Name: "out", Type: "path", Positional: true},
+	)
+	executor := NewCommandExecutor()
+
+	// The managed temp file is outside allowed_paths but is not the caller's
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `printf '<svg xmlns="http:"/>' > "$1"`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", result.Binary.MimeType)
+
+	// A path the caller gives is still checked
+	_, err = executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `true`,
+		"out":    "/etc/umcp.svg",
+	})
+	assert.ErrorContains(t, err, "argument out: path '/etc/umcp.svg' is not in allowed paths")
+}
+
+func TestExecuteCallerOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg, tool := shellTool(config.Output{Type: "file", FileArg: "out"})
+	cfg.Settings.WorkingDir = dir
+	tool.Arguments = append(tool.Arguments,
+		config.Argument{Name: "shell", Type: "string", Positional: true, Default: "sh"},
+		config.Argument{Name: "out", Type: "string", Positional: true},
+	)
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `printf '%%PDF-1.4' > "$1"`,
+		"out":    "report.pdf",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", result.Binary.MimeType)
+	assert.Equal(t, "file://"+filepath.Join(dir, "report.pdf"), result.Binary.URI)
+
+	// A file the command did not write is reported instead of returned
+	_, err = executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `true`,
+		"out":    "missing.pdf",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output file was not written")
+}
+
+func TestDetectMimeType(t *testing.T) {
+	tests := []struct {
+		data     string
+		name     string
+		expected string
+	}{
+		{data: "GIF89a....", expected: "image/gif"},
+		{data: `{"a": 1}`, name: "data.json", expected: "application/json"},
+		{data: `<?xml version="1.0"?><svg/>`, expected: "image/svg+xml"},
+		{data: "plain", name: "notes", expected: "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, detectMimeType([]byte(tt.data), tt.name), tt.data)
+	}
+}
@@ -3,11 +3,13 @@ package executor
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/charignon/umcp/internal/config"
+	"github.com/charignon/umcp/internal/template"
 )
 
 // CommandBuilder builds CLI commands from MCP arguments
@@ -24,7 +26,7 @@ func (b *CommandBuilder) BuildCommand(cfg *config.Config, tool *config.Tool, arg
 
 	// Start with the base command
 	if cfg.Settings.Command != "" {
-		cmd = append(cmd, cfg.Settings.Command)
+		cmd = append(cmd, cfg.Settings.Executable())
 	}
 
 	// Add subcommand if specified
@@ -32,6 +34,9 @@ func (b *CommandBuilder) BuildCommand(cfg *config.Config, tool *config.Tool, arg
 		cmd = append(cmd, tool.Command)
 	}
 
+	// Values seen by argument templates
+	data := templateData(tool, args)
+
 	// Process positional arguments first
 	positionalArgs := b.extractPositionalArgs(tool.Arguments, args)
 	for _, arg := range positionalArgs {
@@ -46,11 +51,17 @@ func (b *CommandBuilder) BuildCommand(cfg *config.Config, tool *config.Tool, arg
 			}
 		}
 
-		strVal, err := b.formatValue(arg.Type, value)
+		if ok, err := b.evaluateCondition(arg, data); err != nil {
+			return nil, fmt.Errorf("argument %s: %w", arg.Name, err)
+		} else if !ok {
+			continue
+		}
+
+		tokens, err := b.positionalTokens(arg, value, data)
 		if err != nil {
 			return nil, fmt.Errorf("failed to format %s: %w", arg.Name, err)
 		}
-		cmd = append(cmd, strVal)
+		cmd = append(cmd, tokens...)
 	}
 
 	// Process flag arguments
@@ -71,12 +82,20 @@ func (b *CommandBuilder) BuildCommand(cfg *config.Config, tool *config.Tool, arg
 		}
 
 		// Handle conditional arguments
-		if arg.When != "" && !b.evaluateCondition(arg.When, args) {
+		if ok, err := b.evaluateCondition(arg, data); err != nil {
+			return nil, fmt.Errorf("argument %s: %w", arg.Name, err)
+		} else if !ok {
 			continue
 		}
 
-		// Build the flag
-		flagParts, err := b.buildFlag(arg, value)
+		// Build the flag, or render the argument's template
+		var flagParts []string
+		var err error
+		if isTemplated(arg) {
+			flagParts, err = b.renderTokens(arg, value, data)
+		} else {
+			flagParts, err = b.buildFlag(arg, value)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to build flag for %s: %w", arg.Name, err)
 		}
@@ -103,6 +122,82 @@ func (b *CommandBuilder) extractPositionalArgs(arguments []config.Argument, args
 	return positional
 }
 
+// templateData returns the values argument templates see: the call's
+// arguments, with defaults filled in and nil for omitted arguments
+func templateData(tool *config.Tool, args map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(args)+len(tool.Arguments))
+	for key, value := range args {
+		data[key] = value
+	}
+	for _, arg := range tool.Arguments {
+		if _, exists := data[arg.Name]; !exists {
+			data[arg.Name] = arg.Default
+		}
+	}
+	return data
+}
+
+// isTemplated reports whether an argument is rendered from templates
+func isTemplated(arg config.Argument) bool {
+	return arg.Template != "" || len(arg.Tokens) > 0
+}
+
+// positionalTokens renders a positional argument. Arrays add one token per
+// item unless they are joined.
+func (b *CommandBuilder) positionalTokens(arg config.Argument, value interface{}, data map[string]interface{}) ([]string, error) {
+	if isTemplated(arg) {
+		return b.renderTokens(arg, value, data)
+	}
+	if hasItems(arg) {
+		items, err := b.formatItems(arg, value)
+		if err != nil {
+			return nil, err
+		}
+		if arg.Join != "" {
+			return []string{strings.Join(items, arg.Join)}, nil
+		}
+		return items, nil
+	}
+	strVal, err := b.formatArgument(arg, value)
+	if err != nil {
+		return nil, err
+	}
+	return []string{strVal}, nil
+}
+
+// renderTokens renders an argument's template or tokens. A false boolean
+// renders its false_flag instead; empty tokens are dropped.
+func (b *CommandBuilder) renderTokens(arg config.Argument, value interface{}, data map[string]interface{}) ([]string, error) {
+	if arg.Type == "boolean" {
+		boolVal, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected boolean, got %T", value)
+		}
+		if !boolVal {
+			if arg.FalseFlag != "" {
+				return []string{arg.FalseFlag}, nil
+			}
+			return []string{}, nil
+		}
+	}
+
+	texts := arg.Tokens
+	if arg.Template != "" {
+		texts = []string{arg.Template}
+	}
+	tokens := []string{}
+	for _, text := range texts {
+		token, err := template.Render(text, data)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
 // buildFlag builds command-line flag(s) from an argument
 func (b *CommandBuilder) buildFlag(arg config.Argument, value interface{}) ([]string, error) {
 	switch arg.Type {
@@ -114,44 +209,140 @@ func (b *CommandBuilder) buildFlag(arg config.Argument, value interface{}) ([]st
 		if boolVal && arg.Flag != "" {
 			return []string{arg.Flag}, nil
 		}
+		if !boolVal && arg.FalseFlag != "" {
+			return []string{arg.FalseFlag}, nil
+		}
 		return []string{}, nil
 
-	case "array":
-		arr, ok := value.([]interface{})
-		if !ok {
-			// Try to convert single value to array
-			arr = []interface{}{value}
+	case "array", "object":
+		if !hasItems(arg) {
+			break
+		}
+		items, err := b.formatItems(arg, value)
+		if err != nil {
+			return nil, err
+		}
+		if arg.Join != "" {
+			return flagWithValue(arg.Flag, strings.Join(items, arg.Join)), nil
 		}
 
+		// Repeat the flag for each item
 		result := []string{}
-		for _, item := range arr {
-			strVal, err := b.formatValue("string", item)
-			if err != nil {
-				return nil, err
+		for _, item := range items {
+			result = append(result, flagWithValue(arg.Flag, item)...)
+		}
+		return result, nil
+	}
+
+	strVal, err := b.formatArgument(arg, value)
+	if err != nil {
+		return nil, err
+	}
+	return flagWithValue(arg.Flag, strVal), nil
+}
+
+// hasItems reports whether an argument renders as a list of items: arrays,
+// and objects rendered as pairs
+func hasItems(arg config.Argument) bool {
+	return arg.Type == "array" || (arg.Type == "object" && arg.Pairs != "")
+}
+
+// formatItems formats an array's items by their declared type, or an
+// object's entries as KEY<pairs>VALUE sorted by key. A scalar passed for an
+// array is a single item.
+func (b *CommandBuilder) formatItems(arg config.Argument, value interface{}) ([]string, error) {
+	if arg.Type == "object" {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object, got %T", value)
+		}
+		if err := checkProperties(arg.Properties, obj); err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			valueType := "string"
+			if property := arg.Properties[key]; property != nil {
+				valueType = property.Type
 			}
-			if strings.Contains(arg.Flag, "=") {
-				result = append(result, fmt.Sprintf("%s%s", arg.Flag, strVal))
-			} else {
-				result = append(result, arg.Flag, strVal)
+			strVal, err := b.formatValue(valueType, obj[key])
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", key, err)
 			}
+			items[i] = key + arg.Pairs + strVal
 		}
-		return result, nil
+		return items, nil
+	}
 
-	default:
-		strVal, err := b.formatValue(arg.Type, value)
+	arr, ok := value.([]interface{})
+	if !ok {
+		arr = []interface{}{value}
+	}
+	itemType := arg.ItemType()
+	items := make([]string, len(arr))
+	for i, item := range arr {
+		if itemType == "object" && arg.Items != nil {
+			if obj, ok := item.(map[string]interface{}); ok {
+				if err := checkProperties(arg.Items.Properties, obj); err != nil {
+					return nil, fmt.Errorf("item %d: %w", i, err)
+				}
+			}
+		}
+		strVal, err := b.formatValue(itemType, item)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("item %d: %w", i, err)
 		}
+		items[i] = strVal
+	}
+	return items, nil
+}
 
-		if arg.Flag == "" {
-			return []string{strVal}, nil
+// formatArgument formats a single-valued argument, checking the required
+// properties of objects
+func (b *CommandBuilder) formatArgument(arg config.Argument, value interface{}) (string, error) {
+	if arg.Type == "object" {
+		if obj, ok := value.(map[string]interface{}); ok {
+			if err := checkProperties(arg.Properties, obj); err != nil {
+				return "", err
+			}
 		}
+	}
+	return b.formatValue(arg.Type, value)
+}
 
-		if strings.Contains(arg.Flag, "=") {
-			return []string{fmt.Sprintf("%s%s", arg.Flag, strVal)}, nil
+// checkProperties reports required properties missing from an object
+func checkProperties(properties map[string]*config.Value, obj map[string]interface{}) error {
+	names := make([]string, 0, len(properties))
+	for name, property := range properties {
+		if property != nil && property.Required {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("missing required property %s", name)
 		}
-		return []string{arg.Flag, strVal}, nil
 	}
+	return nil
+}
+
+// flagWithValue returns "flag value", "flag=value" when the flag ends in
+// "=", or just the value when there is no flag
+func flagWithValue(flag, value string) []string {
+	if flag == "" {
+		return []string{value}
+	}
+	if strings.Contains(flag, "=") {
+		return []string{flag + value}
+	}
+	return []string{flag, value}
 }
 
 // formatValue formats a value according to its type
@@ -160,16 +351,21 @@ func (b *CommandBuilder) formatValue(argType string, value interface{}) (string,
 	case "string":
 		str, ok := value.(string)
 		if !ok {
-			return fmt.Sprintf("%v", value), nil
+			return formatScalar(value), nil
 		}
 		return str, nil
 
 	case "integer":
 		switch v := value.(type) {
 		case float64:
-			return strconv.Itoa(int(v)), nil
+			if v != math.Trunc(v) {
+				return "", fmt.Errorf("expected an integer, got %s", formatScalar(v))
+			}
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
 		case int:
 			return strconv.Itoa(v), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
 		case string:
 			// Parse string to int
 			i, err := strconv.Atoi(v)
@@ -184,15 +380,17 @@ func (b *CommandBuilder) formatValue(argType string, value interface{}) (string,
 	case "float":
 		switch v := value.(type) {
 		case float64:
-			return fmt.Sprintf("%f", v), nil
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
 		case int:
-			return fmt.Sprintf("%f", float64(v)), nil
+			return strconv.Itoa(v), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
 		case string:
 			f, err := strconv.ParseFloat(v, 64)
 			if err != nil {
 				return "", fmt.Errorf("invalid float: %s", v)
 			}
-			return fmt.Sprintf("%f", f), nil
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
 		default:
 			return "", fmt.Errorf("expected float, got %T", value)
 		}
@@ -209,30 +407,12 @@ func (b *CommandBuilder) formatValue(argType string, value interface{}) (string,
 	}
 }
 
-// evaluateCondition evaluates a simple condition expression
-func (b *CommandBuilder) evaluateCondition(condition string, args map[string]interface{}) bool {
-	// Simple implementation - can be extended
-	// Format: "${varname} == value"
-	parts := strings.Split(condition, " ")
-	if len(parts) != 3 {
-		return false
+// evaluateCondition reports whether an argument's when condition holds.
+// Conditions see the same values as templates, so defaults apply.
+func (b *CommandBuilder) evaluateCondition(arg config.Argument, data map[string]interface{}) (bool, error) {
+	condition, err := arg.Condition()
+	if err != nil || condition == nil {
+		return err == nil, err
 	}
-
-	varName := strings.TrimPrefix(strings.TrimSuffix(parts[0], "}"), "${")
-	operator := parts[1]
-	expectedValue := strings.Trim(parts[2], "\"")
-
-	actualValue, exists := args[varName]
-	if !exists {
-		return false
-	}
-
-	switch operator {
-	case "==":
-		return fmt.Sprintf("%v", actualValue) == expectedValue
-	case "!=":
-		return fmt.Sprintf("%v", actualValue) != expectedValue
-	default:
-		return false
-	}
-}
\ No newline at end of file
+	return condition.Eval(data)
+}
@@ -153,6 +153,144 @@ func TestBuildCommand(t *testing.T) {
 			},
 			expected: []string{"test", "--debug", "-v"},
 		},
+		{
+			name: "command with templated arguments",
+			cfg: &config.Config{
+				Settings: config.Settings{
+					Command: "git",
+				},
+			},
+			tool: &config.Tool{
+				Command: "log",
+				Arguments: []config.Argument{
+					{
+						Name:     "days",
+						Type:     "integer",
+						Template: "--since={{.days}}d",
+					},
+					{
+						Name:     "fields",
+						Type:     "array",
+						Template: `--format={{join .fields "%x09"}}`,
+					},
+					{
+						Name:   "author",
+						Type:   "string",
+						Tokens: []string{"--author", "${author}", `{{if .days}}--no-merges{{end}}`},
+					},
+					{
+						Name:    "range",
+						Type:    "string",
+						Default: "HEAD",
+						Tokens:  []string{`{{.range}}{{if .base}}...{{.base}}{{end}}`},
+					},
+					{
+						Name: "base",
+						Type: "string",
+					},
+				},
+			},
+			args: map[string]interface{}{
+				"days":   float64(7),
+				"fields": []interface{}{"%h", "%s"},
+				"author": "Ana",
+			},
+			expected: []string{"git", "log", "--since=7d", "--format=%h%x09%s", "--author", "Ana", "--no-merges", "HEAD"},
+		},
+		{
+			name: "command with false flags and joined arrays",
+			cfg: &config.Config{
+				Settings: config.Settings{
+					Command: "tool",
+				},
+			},
+			tool: &config.Tool{
+				Arguments: []config.Argument{
+					{
+						Name:       "files",
+						Type:       "array",
+						Positional: true,
+						Position:   0,
+					},
+					{
+						Name:      "color",
+						Type:      "boolean",
+						Flag:      "--color",
+						FalseFlag: "--no-color",
+					},
+					{
+						Name:      "pager",
+						Type:      "boolean",
+						Template:  "--pager={{.pager}}",
+						FalseFlag: "--no-pager",
+					},
+					{
+						Name: "labels",
+						Type: "array",
+						Flag: "--label",
+						Join: ",",
+					},
+					{
+						Name: "tags",
+						Type: "array",
+						Flag: "--tags=",
+						Join: ":",
+					},
+				},
+			},
+			args: map[string]interface{}{
+				"files":  []interface{}{"a.txt", "b c.txt"},
+				"color":  false,
+				"pager":  false,
+				"labels": []interface{}{"bug", "ui"},
+				"tags":   []interface{}{"x", float64(2)},
+			},
+			expected: []string{"tool", "a.txt", "b c.txt", "--no-color", "--no-pager", "--label", "bug,ui", "--tags=x:2"},
+		},
+		{
+			name: "command with typed items and pairs",
+			cfg: &config.Config{
+				Settings: config.Settings{
+					Command: "docker",
+				},
+			},
+			tool: &config.Tool{
+				Command: "run",
+				Arguments: []config.Argument{
+					{
+						Name:  "ports",
+						Type:  "array",
+						Flag:  "-p",
+						Items: &config.Value{Type: "integer"},
+					},
+					{
+						Name:  "env",
+						Type:  "object",
+						Flag:  "-e",
+						Pairs: "=",
+					},
+					{
+						Name:  "labels",
+						Type:  "object",
+						Flag:  "--label",
+						Pairs: "=",
+						Join:  ",",
+					},
+					{
+						Name: "config",
+						Type: "object",
+						Flag: "--config",
+					},
+				},
+			},
+			args: map[string]interface{}{
+				"ports":  []interface{}{float64(8080), "9090"},
+				"env":    map[string]interface{}{"B": "two words", "A": float64(1)},
+				"labels": map[string]interface{}{"team": "ui", "app": "web"},
+				"config": map[string]interface{}{"debug": true},
+			},
+			expected: []string{"docker", "run", "-p", "8080", "-p", "9090", "-e", "A=1", "-e", "B=two words", "--label", "app=web,team=ui", "--config", `{"debug":true}`},
+		},
 	}
 
 	for _, tt := range tests {
@@ -206,6 +344,26 @@ func TestBuildCommandErrors(t *testing.T) {
 	}
 }
 
+func TestBuildCommandValueErrors(t *testing.T) {
+	builder := NewCommandBuilder()
+	cfg := &config.Config{Settings: config.Settings{Command: "test"}}
+	tool := &config.Tool{
+		Arguments: []config.Argument{
+			{Name: "ids", Type: "array", Flag: "--id", Items: &config.Value{Type: "integer"}},
+			{Name: "target", Type: "object", Flag: "--target", Properties: map[string]*config.Value{
+				"host": {Type: "string", Required: true},
+				"port": {Type: "integer"},
+			}},
+		},
+	}
+
+	_, err := builder.BuildCommand(cfg, tool, map[string]interface{}{"ids": []interface{}{float64(1), "x"}})
+	assert.EqualError(t, err, "failed to build flag for ids: item 1: invalid integer: x")
+
+	_, err = builder.BuildCommand(cfg, tool, map[string]interface{}{"target": map[string]interface{}{"port": float64(22)}})
+	assert.EqualError(t, err, "failed to build flag for target: missing required property host")
+}
+
 func TestFormatValue(t *testing.T) {
 	builder := NewCommandBuilder()
 
@@ -237,7 +395,31 @@ func TestFormatValue(t *testing.T) {
 			name:     "float value",
 			argType:  "float",
 			value:    3.14,
-			expected: "3.140000",
+			expected: "3.14",
+		},
+		{
+			name:     "float keeps small values",
+			argType:  "float",
+			value:    1e-9,
+			expected: "0.000000001",
+		},
+		{
+			name:     "float shortest form",
+			argType:  "float",
+			value:    0.1,
+			expected: "0.1",
+		},
+		{
+			name:     "integer from int64",
+			argType:  "integer",
+			value:    int64(1) << 40,
+			expected: "1099511627776",
+		},
+		{
+			name:     "string from float64",
+			argType:  "string",
+			value:    1.5e6,
+			expected: "1500000",
 		},
 		{
 			name:     "object as JSON",
@@ -256,6 +438,11 @@ func TestFormatValue(t *testing.T) {
 	}
 }
 
+func TestFormatValueRejectsFractionalIntegers(t *testing.T) {
+	_, err := NewCommandBuilder().formatValue("integer", 1.7)
+	assert.EqualError(t, err, "expected an integer, got 1.7")
+}
+
 func TestEvaluateCondition(t *testing.T) {
 	builder := NewCommandBuilder()
 
@@ -289,11 +476,24 @@ func TestEvaluateCondition(t *testing.T) {
 			args:      map[string]interface{}{},
 			expected:  false,
 		},
+		{
+			name:      "value with spaces",
+			condition: `${message} == "fix the build"`,
+			args:      map[string]interface{}{"message": "fix the build"},
+			expected:  true,
+		},
+		{
+			name:      "numeric comparison and presence",
+			condition: "exists(limit) && ${limit} >= 10 || ${mode} in [ci, release]",
+			args:      map[string]interface{}{"limit": float64(5), "mode": "ci"},
+			expected:  true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := builder.evaluateCondition(tt.condition, tt.args)
+			result, err := builder.evaluateCondition(config.Argument{When: tt.condition}, tt.args)
+			require.NoError(t, err)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -0,0 +1,150 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charignon/umcp/internal/config"
+)
+
+// resultCache holds results of tools with a cache: block. Entries are keyed
+// by the built command line, so arguments that build the same command
+// (defaults, false booleans, argument order) share an entry.
+type resultCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	config  string
+	tool    string
+	result  Result
+	expires time.Time
+	watch   string // Signature of the watched files when the result was stored
+}
+
+func newResultCache() *resultCache {
+	return &resultCache{
+		entries: make(map[string]*cacheEntry),
+		now:     time.Now,
+	}
+}
+
+// cacheKey identifies a call by tool, working directory, command line and
+// the environment variables added for it
+func cacheKey(cfg *config.Config, tool *config.Tool, workingDir string, cmdParts, env []string) string {
+	h := sha256.New()
+	parts := append([]string{cfg.Metadata.Name, tool.Name, workingDir}, cmdParts...)
+	for _, part := range append(parts, env...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns a copy of a cached result that has not expired and whose
+// watched files are unchanged
+func (c *resultCache) get(key string, tool *config.Tool, workingDir string) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expires) || entry.watch != watchSignature(tool.Cache.Watch, workingDir) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	result := entry.result
+	return &result, true
+}
+
+// put stores a result and drops expired entries
+func (c *resultCache) put(key string, cfg *config.Config, tool *config.Tool, workingDir string, result *Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored := *result
+	stored.rawStdout = ""
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = &cacheEntry{
+		config:  cfg.Metadata.Name,
+		tool:    tool.Name,
+		result:  stored,
+		expires: now.Add(tool.Cache.TTL),
+		watch:   watchSignature(tool.Cache.Watch, workingDir),
+	}
+}
+
+// invalidate drops the results of every tool whose invalidated_by lists
+// the tool that was just called
+func (c *resultCache) invalidate(cfg *config.Config, called string) {
+	stale := map[string]bool{}
+	for _, tool := range cfg.Tools {
+		if tool.Cache == nil {
+			continue
+		}
+		for _, name := range tool.Cache.InvalidatedBy {
+			if name == called {
+				stale[tool.Name] = true
+			}
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if entry.config == cfg.Metadata.Name && stale[entry.tool] {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// watchSignature summarizes the size and modification time of the files
+// matching the watch patterns. Relative patterns are resolved against the
+// working directory.
+func watchSignature(patterns []string, workingDir string) string {
+	if len(patterns) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(workingDir, pattern)
+		}
+		matches, _ := filepath.Glob(pattern)
+		if len(matches) == 0 {
+			fmt.Fprintf(&sb, "%s:missing\n", pattern)
+			continue
+		}
+		sort.Strings(matches)
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				fmt.Fprintf(&sb, "%s:missing\n", path)
+				continue
+			}
+			fmt.Fprintf(&sb, "%s:%d:%d\n", path, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return sb.String()
+}
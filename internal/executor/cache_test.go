@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stageRecorder keeps the stages and last command reported during execution
+type stageRecorder struct {
+	stages []Stage
+	argv   []string
+	env    []string
+}
+
+func (r *stageRecorder) TraceCommand(command string, args []string, workingDir string, env []string) {
+	r.argv = append([]string{command}, args...)
+	r.env = env
+}
+
+func (r *stageRecorder) TraceCommandOutput(string, int, error) {}
+
+func (r *stageRecorder) TraceStage(stage Stage) {
+	r.stages = append(r.stages, stage)
+}
+
+// countingScript appends to a file on every run and prints the run count
+func countingScript(t *testing.T) string {
+	counter := filepath.Join(t.TempDir(), "runs")
+	return fmt.Sprintf("echo run >> %s; wc -l < %s | tr -d ' '", counter, counter)
+}
+
+func TestExecuteCacheHitAndExpiry(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	tool.Cache = &config.Cache{TTL: time.Minute}
+	executor := NewCommandExecutor()
+	recorder := &stageRecorder{}
+	executor.SetTracer(recorder)
+
+	now := time.Now()
+	executor.cache.now = func() time.Time { return now }
+	args := map[string]interface{}{"script": countingScript(t)}
+
+	result, err := executor.Execute(cfg, tool, args)
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", result.Output)
+
+	result, err = executor.Execute(cfg, tool, args)
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", result.Output)
+
+	var hits []interface{}
+	for _, stage := range recorder.stages {
+		if stage.Name == "cache" {
+			hits = append(hits, stage.Attributes["hit"])
+		}
+	}
+	assert.Equal(t, []interface{}{false, true}, hits)
+
+	now = now.Add(time.Minute)
+	result, err = executor.Execute(cfg, tool, args)
+	require.NoError(t, err)
+	assert.Equal(t, "2\n", result.Output)
+}
+
+func TestExecuteCacheKeyedByCommand(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	tool.Arguments = append(tool.Arguments,
+		config.Argument{Name: "shell", Type: "string", Positional: true, Position: 1, Default: "sh"},
+		config.Argument{Name: "label", Type: "string", Positional: true, Position: 2},
+	)
+	tool.Cache = &config.Cache{TTL: time.Minute}
+	executor := NewCommandExecutor()
+	script := countingScript(t)
+
+	// An explicit default builds the same command as an omitted one
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{"script": script})
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", result.Output)
+	result, err = executor.Execute(cfg, tool, map[string]interface{}{"script": script, "shell": "sh"})
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", result.Output)
+
+	result, err = executor.Execute(cfg, tool, map[string]interface{}{"script": script, "label": "other"})
+	require.NoError(t, err)
+	assert.Equal(t, "2\n", result.Output)
+}
+
+func TestExecuteCacheSkipsFailures(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	tool.Cache = &config.Cache{TTL: time.Minute}
+	executor := NewCommandExecutor()
+	script := countingScript(t) + "; exit 1"
+
+	_, err := executor.Execute(cfg, tool, map[string]interface{}{"script": script})
+	require.Error(t, err)
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{"script": script})
+	require.Error(t, err)
+	assert.Equal(t, "2\n", result.Stdout)
+}
+
+func TestExecuteCacheWatch(t *testing.T) {
+	dir := t.TempDir()
+	watched := filepath.Join(dir, "HEAD")
+	require.NoError(t, os.WriteFile(watched, []byte("a"), 0644))
+
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	tool.Cache = &config.Cache{TTL: time.Minute, Watch: []string{filepath.Join(dir, "*")}}
+	executor := NewCommandExecutor()
+	args := map[string]interface{}{"script": countingScript(t)}
+
+	result, err := executor.Execute(cfg, tool, args)
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", result.Output)
+	result, err = executor.Execute(cfg, tool, args)
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", result.Output)
+
+	require.NoError(t, os.WriteFile(watched, []byte("changed"), 0644))
+	result, err = executor.Execute(cfg, tool, args)
+	require.NoError(t, err)
+	assert.Equal(t, "2\n", result.Output)
+
+	// A new file matching the pattern also counts as a change
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ORIG_HEAD"), nil, 0644))
+	result, err = executor.Execute(cfg, tool, args)
+	require.NoError(t, err)
+	assert.Equal(t, "3\n", result.Output)
+}
+
+func TestExecuteCacheInvalidatedBy(t *testing.T) {
+	cfg, status := shellTool(config.Output{Type: "raw"})
+	status.Name = "status"
+	status.Cache = &config.Cache{TTL: time.Minute, InvalidatedBy: []string{"commit"}}
+	_, commit := shellTool(config.Output{Type: "raw"})
+	commit.Name = "commit"
+	cfg.Tools = []config.Tool{*status, *commit}
+	executor := NewCommandExecutor()
+	args := map[string]interface{}{"script": countingScript(t)}
+
+	result, err := executor.Execute(cfg, status, args)
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", result.Output)
+
+	// A failed call invalidates too, since it may have changed state
+	_, err = executor.Execute(cfg, commit, map[string]interface{}{"script": "exit 1"})
+	require.Error(t, err)
+
+	result, err = executor.Execute(cfg, status, args)
+	require.NoError(t, err)
+	assert.Equal(t, "2\n", result.Output)
+}
@@ -0,0 +1,228 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/charignon/umcp/internal/config"
+)
+
+// coerceArguments converts call arguments to their declared types before
+// the command is built: integers become int64, floats float64, and array
+// items and object properties are converted by their declared types. In
+// strict mode values must already have the right JSON type; lenient mode
+// also accepts numeric strings, "true"/"false" for booleans, numbers and
+// booleans for strings, and a single value for an array. Null values are
+// treated as omitted and undeclared arguments are passed through. Every
+// type error is reported, each with its argument name.
+func coerceArguments(tool *config.Tool, args map[string]interface{}, strict bool) (map[string]interface{}, error) {
+	coerced := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		if value != nil {
+			coerced[name] = value
+		}
+	}
+
+	// confirm_when adds a boolean confirm argument unless the tool declares one
+	arguments := tool.Arguments
+	if tool.ConfirmWhen != "" && tool.Argument("confirm") == nil {
+		arguments = append(arguments[:len(arguments):len(arguments)], config.Argument{Name: "confirm", Type: "boolean"})
+	}
+
+	var problems []string
+	for _, arg := range arguments {
+		value, ok := coerced[arg.Name]
+		if !ok {
+			continue
+		}
+		spec := &config.Value{Type: arg.Type, Items: arg.Items, Properties: arg.Properties}
+		converted, err := coerceValue(spec, value, strict)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", arg.Name, err))
+			continue
+		}
+		coerced[arg.Name] = converted
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return coerced, nil
+}
+
+// coerceValue converts one value to the type a value description declares
+func coerceValue(spec *config.Value, value interface{}, strict bool) (interface{}, error) {
+	switch spec.Type {
+	case "integer":
+		return coerceInteger(value, strict)
+	case "float":
+		return coerceFloat(value, strict)
+	case "boolean":
+		return coerceBoolean(value, strict)
+	case "array":
+		return coerceArray(spec, value, strict)
+	case "object":
+		return coerceObject(spec, value, strict)
+	default:
+		return coerceString(value, strict)
+	}
+}
+
+func coerceInteger(value interface{}, strict bool) (interface{}, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		return integral(v, value)
+	case string:
+		if !strict {
+			if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return i, nil
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return integral(f, value)
+			}
+		}
+	}
+	return nil, typeError("an integer", value)
+}
+
+// integral converts a float holding a whole number to int64
+func integral(f float64, value interface{}) (interface{}, error) {
+	if f != math.Trunc(f) || math.IsInf(f, 0) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return nil, typeError("an integer", value)
+	}
+	return int64(f), nil
+}
+
+func coerceFloat(value interface{}, strict bool) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		if !strict {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return f, nil
+			}
+		}
+	}
+	return nil, typeError("a number", value)
+}
+
+func coerceBoolean(value interface{}, strict bool) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if !strict {
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			}
+		}
+	}
+	return nil, typeError("a boolean", value)
+}
+
+func coerceString(value interface{}, strict bool) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool, int, int64, float64:
+		if !strict {
+			return formatScalar(v), nil
+		}
+	}
+	return nil, typeError("a string", value)
+}
+
+func coerceArray(spec *config.Value, value interface{}, strict bool) (interface{}, error) {
+	arr, ok := value.([]interface{})
+	if !ok {
+		if strict {
+			return nil, typeError("an array", value)
+		}
+		if _, isObject := value.(map[string]interface{}); isObject {
+			return nil, typeError("an array", value)
+		}
+		arr = []interface{}{value}
+	}
+
+	itemSpec := spec.Items
+	if itemSpec == nil {
+		itemSpec = &config.Value{Type: "string"}
+	}
+	items := make([]interface{}, len(arr))
+	for i, item := range arr {
+		converted, err := coerceValue(itemSpec, item, strict)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		items[i] = converted
+	}
+	return items, nil
+}
+
+func coerceObject(spec *config.Value, value interface{}, strict bool) (interface{}, error) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, typeError("an object", value)
+	}
+	if len(spec.Properties) == 0 {
+		return obj, nil
+	}
+
+	result := make(map[string]interface{}, len(obj))
+	for key, item := range obj {
+		property := spec.Properties[key]
+		if property == nil || item == nil {
+			result[key] = item
+			continue
+		}
+		converted, err := coerceValue(property, item, strict)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", key, err)
+		}
+		result[key] = converted
+	}
+	return result, nil
+}
+
+// typeError describes a value of the wrong type
+func typeError(expected string, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return fmt.Errorf("expected %s, got %q", expected, v)
+	case bool, int, int64, float64:
+		return fmt.Errorf("expected %s, got %s", expected, formatScalar(v))
+	case []interface{}:
+		return fmt.Errorf("expected %s, got an array", expected)
+	case map[string]interface{}:
+		return fmt.Errorf("expected %s, got an object", expected)
+	default:
+		return fmt.Errorf("expected %s, got %T", expected, value)
+	}
+}
+
+// formatScalar formats a number or boolean, with floats in their shortest
+// round-trip form
+func formatScalar(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
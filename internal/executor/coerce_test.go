@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func coercionTool() *config.Tool {
+	return &config.Tool{
+		Arguments: []config.Argument{
+			{Name: "limit", Type: "integer"},
+			{Name: "ratio", Type: "float"},
+			{Name: "debug", Type: "boolean"},
+			{Name: "name", Type: "string"},
+			{Name: "ports", Type: "array", Items: &config.Value{Type: "integer"}},
+			{Name: "target", Type: "object", Properties: map[string]*config.Value{
+				"port": {Type: "integer"},
+			}},
+		},
+	}
+}
+
+func TestCoerceArgumentsLenient(t *testing.T) {
+	args, err := coerceArguments(coercionTool(), map[string]interface{}{
+		"limit":  "10",
+		"ratio":  "0.5",
+		"debug":  "False",
+		"name":   float64(42),
+		"ports":  "8080",
+		"target": map[string]interface{}{"port": "22", "host": "example.com"},
+		"other":  "kept",
+		"empty":  nil,
+	}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"limit":  int64(10),
+		"ratio":  0.5,
+		"debug":  false,
+		"name":   "42",
+		"ports":  []interface{}{int64(8080)},
+		"target": map[string]interface{}{"port": int64(22), "host": "example.com"},
+		"other":  "kept",
+	}, args)
+}
+
+func TestCoerceArgumentsStrict(t *testing.T) {
+	args, err := coerceArguments(coercionTool(), map[string]interface{}{
+		"limit": float64(10),
+		"ratio": float64(2),
+		"debug": true,
+		"ports": []interface{}{float64(80)},
+	}, true)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"limit": int64(10),
+		"ratio": float64(2),
+		"debug": true,
+		"ports": []interface{}{int64(80)},
+	}, args)
+
+	_, err = coerceArguments(coercionTool(), map[string]interface{}{
+		"limit": "10",
+		"debug": "true",
+		"name":  float64(1),
+		"ports": float64(80),
+	}, true)
+	assert.EqualError(t, err, `limit: expected an integer, got "10"; debug: expected a boolean, got "true"; `+
+		`name: expected a string, got 1; ports: expected an array, got 80`)
+}
+
+func TestCoerceArgumentsErrors(t *testing.T) {
+	_, err := coerceArguments(coercionTool(), map[string]interface{}{
+		"limit":  1.7,
+		"ratio":  "fast",
+		"debug":  "abc",
+		"ports":  []interface{}{float64(80), "http"},
+		"target": map[string]interface{}{"port": 22.5},
+	}, false)
+	assert.EqualError(t, err, `limit: expected an integer, got 1.7; ratio: expected a number, got "fast"; `+
+		`debug: expected a boolean, got "abc"; ports: item 1: expected an integer, got "http"; `+
+		`target: property port: expected an integer, got 22.5`)
+}
+
+func TestCoerceConfirmArgument(t *testing.T) {
+	tool := &config.Tool{ConfirmWhen: "${force}", Arguments: []config.Argument{{Name: "force", Type: "boolean"}}}
+
+	args, err := coerceArguments(tool, map[string]interface{}{"force": "true", "confirm": "true"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, true, args["confirm"])
+	assert.NoError(t, checkConditions(tool, args))
+
+	_, err = coerceArguments(tool, map[string]interface{}{"confirm": "true"}, true)
+	assert.EqualError(t, err, `confirm: expected a boolean, got "true"`)
+	assert.Len(t, tool.Arguments, 1)
+}
+
+func TestExecuteCoercesArguments(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	tool.Arguments = append(tool.Arguments,
+		config.Argument{Name: "ratio", Type: "float", Positional: true, Position: 1},
+		config.Argument{Name: "count", Type: "integer", Positional: true, Position: 2},
+	)
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `echo "$0 $1"`, "ratio": 1e-9, "count": "3",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0.000000001 3\n", result.Output)
+
+	_, err = executor.Execute(cfg, tool, map[string]interface{}{"script": "true", "ratio": "x", "count": 2.5})
+	assert.EqualError(t, err, `invalid arguments: ratio: expected a number, got "x"; count: expected an integer, got 2.5`)
+}
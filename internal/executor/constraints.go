@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charignon/umcp/internal/config"
+)
+
+// checkConstraints reports the first of a tool's constraints that the call's
+// arguments break. Only arguments the caller gave count; defaults do not.
+func checkConstraints(tool *config.Tool, args map[string]interface{}) error {
+	for _, constraint := range tool.Constraints {
+		if err := checkConstraint(constraint, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkConditions evaluates a tool's when and confirm_when conditions. Like
+// argument conditions they see defaults. A call that confirm_when applies to
+// must set confirm to true.
+func checkConditions(tool *config.Tool, args map[string]interface{}) error {
+	data := templateData(tool, args)
+
+	condition, err := tool.Condition()
+	if err == nil && condition != nil {
+		var ok bool
+		if ok, err = condition.Eval(data); err == nil && !ok {
+			return fmt.Errorf("tool %s only runs when %s", tool.Name, condition)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("when: %w", err)
+	}
+
+	condition, err = tool.ConfirmCondition()
+	if err == nil && condition != nil {
+		var ok bool
+		if ok, err = condition.Eval(data); err == nil && ok && args["confirm"] != true {
+			return fmt.Errorf("tool %s needs confirmation when %s: call it again with confirm: true", tool.Name, condition)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("confirm_when: %w", err)
+	}
+	return nil
+}
+
+func checkConstraint(constraint config.Constraint, args map[string]interface{}) error {
+	switch {
+	case len(constraint.Exclusive) > 0:
+		if given := givenArgs(constraint.Exclusive, args); len(given) > 1 {
+			return fmt.Errorf("arguments %s cannot be used together", strings.Join(given, " and "))
+		}
+
+	case len(constraint.OneOf) > 0:
+		given := givenArgs(constraint.OneOf, args)
+		if len(given) > 1 {
+			return fmt.Errorf("arguments %s cannot be used together", strings.Join(given, " and "))
+		}
+		if len(given) == 0 {
+			return fmt.Errorf("one of %s is required", strings.Join(constraint.OneOf, ", "))
+		}
+
+	case len(constraint.AtLeastOne) > 0:
+		if len(givenArgs(constraint.AtLeastOne, args)) == 0 {
+			return fmt.Errorf("at least one of %s is required", strings.Join(constraint.AtLeastOne, ", "))
+		}
+
+	default:
+		names := make([]string, 0, len(constraint.Requires))
+		for name := range constraint.Requires {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !isGiven(args[name]) {
+				continue
+			}
+			for _, required := range constraint.Requires[name] {
+				if !isGiven(args[required]) {
+					return fmt.Errorf("argument %s requires %s", name, required)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// givenArgs returns the names the caller gave a value for
+func givenArgs(names []string, args map[string]interface{}) []string {
+	var given []string
+	for _, name := range names {
+		if isGiven(args[name]) {
+			given = append(given, name)
+		}
+	}
+	return given
+}
+
+// isGiven reports whether a value counts as given: false, "" and empty
+// lists mean the same as leaving the argument out
+func isGiven(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConstraints(t *testing.T) {
+	tool := &config.Tool{
+		Constraints: []config.Constraint{
+			{Exclusive: []string{"oneline", "format"}},
+			{Requires: map[string][]string{"ports": {"image"}}},
+			{OneOf: []string{"image", "dockerfile"}},
+			{AtLeastOne: []string{"paths", "all"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "valid",
+			args: map[string]interface{}{"oneline": true, "image": "alpine", "ports": []interface{}{"80"}, "all": true},
+		},
+		{
+			name: "false and empty values are not given",
+			args: map[string]interface{}{"oneline": false, "format": "%h", "dockerfile": "Dockerfile", "image": "", "ports": []interface{}{}, "paths": []interface{}{"a"}},
+		},
+		{
+			name:    "exclusive",
+			args:    map[string]interface{}{"oneline": true, "format": "%h", "image": "alpine", "all": true},
+			wantErr: "arguments oneline and format cannot be used together",
+		},
+		{
+			name:    "requires",
+			args:    map[string]interface{}{"ports": []interface{}{"80"}, "dockerfile": "Dockerfile", "all": true},
+			wantErr: "argument ports requires image",
+		},
+		{
+			name:    "one of missing",
+			args:    map[string]interface{}{"all": true},
+			wantErr: "one of image, dockerfile is required",
+		},
+		{
+			name:    "one of both given",
+			args:    map[string]interface{}{"image": "alpine", "dockerfile": "Dockerfile", "all": true},
+			wantErr: "arguments image and dockerfile cannot be used together",
+		},
+		{
+			name:    "at least one",
+			args:    map[string]interface{}{"image": "alpine", "all": false},
+			wantErr: "at least one of paths, all is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConstraints(tool, tt.args)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteChecksConstraints(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	tool.Arguments = append(tool.Arguments, config.Argument{Name: "quiet", Type: "boolean"})
+	tool.Constraints = []config.Constraint{{Exclusive: []string{"script", "quiet"}}}
+
+	_, err := NewCommandExecutor().Execute(cfg, tool, map[string]interface{}{"script": "echo hi", "quiet": true})
+	assert.EqualError(t, err, "invalid arguments: arguments script and quiet cannot be used together")
+}
+
+func TestCheckConditions(t *testing.T) {
+	tool := &config.Tool{
+		Name:        "push",
+		When:        "${branch} != main or ${force}",
+		ConfirmWhen: "${force}",
+		Arguments: []config.Argument{
+			{Name: "branch", Type: "string", Default: "main"},
+			{Name: "force", Type: "boolean"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{name: "condition holds", args: map[string]interface{}{"branch": "topic"}},
+		{
+			name:    "condition does not hold with the default",
+			args:    map[string]interface{}{},
+			wantErr: "tool push only runs when ${branch} != main or ${force}",
+		},
+		{
+			name:    "needs confirmation",
+			args:    map[string]interface{}{"force": true},
+			wantErr: "tool push needs confirmation when ${force}: call it again with confirm: true",
+		},
+		{name: "confirmed", args: map[string]interface{}{"force": true, "confirm": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConditions(tool, tt.args)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charignon/umcp/internal/config"
+)
+
+// ErrorInfo is the friendly description of a failed command produced by a
+// matching error rule
+type ErrorInfo struct {
+	Message   string `json:"message"`
+	Hint      string `json:"hint,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
+
+// placeholderPattern matches ${name} placeholders in error messages
+var placeholderPattern = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// matchErrorRule finds the first error rule matching a failed command, with
+// tool rules taking precedence over config rules, and renders its message
+func matchErrorRule(cfg *config.Config, tool *config.Tool, result *Result, args map[string]interface{}) *ErrorInfo {
+	for _, rules := range [][]config.ErrorRule{tool.Errors, cfg.Errors} {
+		if info := matchRules(rules, result, args); info != nil {
+			return info
+		}
+	}
+	return nil
+}
+
+// matchRules returns the rendered message of the first matching rule
+func matchRules(rules []config.ErrorRule, result *Result, args map[string]interface{}) *ErrorInfo {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.ExitCode != nil && *rule.ExitCode != result.ExitCode {
+			continue
+		}
+
+		vars := map[string]string{
+			"exit_code": strconv.Itoa(result.ExitCode),
+			"stderr":    strings.TrimSpace(result.Stderr),
+		}
+
+		re, err := rule.StderrRegexp()
+		if err != nil {
+			continue
+		}
+		if re != nil {
+			match := re.FindStringSubmatch(result.Stderr)
+			if match == nil {
+				continue
+			}
+			for j, name := range re.SubexpNames() {
+				if name != "" && j < len(match) {
+					vars[name] = match[j]
+				}
+			}
+		}
+
+		return &ErrorInfo{
+			Message:   expandPlaceholders(rule.Message, vars, args),
+			Hint:      expandPlaceholders(rule.Hint, vars, args),
+			Retryable: rule.Retryable,
+		}
+	}
+	return nil
+}
+
+// expandPlaceholders replaces ${name} with match variables or argument
+// values. Unknown placeholders are left untouched.
+func expandPlaceholders(input string, vars map[string]string, args map[string]interface{}) string {
+	return placeholderPattern.ReplaceAllStringFunc(input, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if value, ok := args[name]; ok {
+			return fmt.Sprintf("%v", value)
+		}
+		return placeholder
+	})
+}
@@ -3,6 +3,8 @@ package executor
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,20 +14,43 @@ import (
 
 	"github.com/charignon/umcp/internal/config"
 	"github.com/charignon/umcp/internal/parser"
+	"github.com/charignon/umcp/internal/schema"
+	"github.com/charignon/umcp/internal/template"
 	"github.com/rs/zerolog/log"
 )
 
+// ErrTimeout is returned when a command exceeds its timeout
+var ErrTimeout = errors.New("command timed out")
+
 // Tracer interface for command tracing
 type Tracer interface {
 	TraceCommand(command string, args []string, workingDir string, env []string)
 	TraceCommandOutput(output string, exitCode int, err error)
 }
 
+// Stage describes one completed step of a tool execution
+type Stage struct {
+	Name       string // "build", "sandbox", "cache", "exec" or "parse"
+	Config     string
+	Tool       string
+	Start      time.Time
+	Duration   time.Duration
+	Attributes map[string]interface{}
+	Err        error
+}
+
+// StageTracer is implemented by tracers that also want timing for each
+// execution stage
+type StageTracer interface {
+	TraceStage(stage Stage)
+}
+
 // CommandExecutor executes CLI commands with sandboxing
 type CommandExecutor struct {
 	builder *CommandBuilder
 	sandbox *Sandbox
-	tracer  Tracer
+	tracers []Tracer
+	cache   *resultCache
 }
 
 // NewCommandExecutor creates a new command executor
@@ -33,26 +58,69 @@ func NewCommandExecutor() *CommandExecutor {
 	return &CommandExecutor{
 		builder: NewCommandBuilder(),
 		sandbox: NewSandbox(),
-		tracer:  nil, // Will be set by SetTracer
+		tracers: nil, // Will be set by SetTracer/AddTracer
+		cache:   newResultCache(),
 	}
 }
 
-// SetTracer sets the tracer for command execution
+// SetTracer sets the tracer for command execution, replacing any others
 func (e *CommandExecutor) SetTracer(tracer Tracer) {
-	e.tracer = tracer
+	e.tracers = []Tracer{tracer}
 }
 
-// Execute runs a command and returns the output
-func (e *CommandExecutor) Execute(cfg *config.Config, tool *config.Tool, args map[string]interface{}) (string, error) {
-	// Build the command
-	cmdParts, err := e.builder.BuildCommand(cfg, tool, args)
+// AddTracer registers an additional tracer for command execution
+func (e *CommandExecutor) AddTracer(tracer Tracer) {
+	e.tracers = append(e.tracers, tracer)
+}
+
+// Result holds the outcome of a tool execution
+type Result struct {
+	Output   string        // Parsed stdout, or raw stdout if parsing failed
+	Stdout   string        // Captured stdout
+	Stderr   string        // Captured stderr
+	ExitCode int           // Process exit code, -1 if it did not exit normally
+	Duration time.Duration // Time spent running the process
+	Error    *ErrorInfo    // Friendly description of a failure, from the errors: rules
+
+	// Remainder holds output cut off by max_output_size or a max_tokens
+	// transform; PageSize is the limit that was applied
+	Remainder string
+	PageSize  int
+
+	// Structured is the decoded output once it has matched the output
+	// schema; Warnings describe problems that did not fail the call
+	Structured interface{}
+	Warnings   []string
+
+	// Binary holds image, file and blob output
+	Binary *Binary
+
+	// Attempts is how many times the command ran, more than one when
+	// failures were retried
+	Attempts int
+
+	rawStdout string // stdout before max_output_size truncation
+}
+
+// Execute runs a command and returns its result. When the command fails the
+// result still carries whatever stdout and stderr were captured.
+func (e *CommandExecutor) Execute(cfg *config.Config, tool *config.Tool, args map[string]interface{}) (*Result, error) {
+	return e.ExecuteContext(context.Background(), cfg, tool, args)
+}
+
+// ExecuteContext is Execute with a context bounding the whole call. Each run
+// of the command is limited by settings.timeout; retries stop when ctx is
+// canceled or its deadline leaves no time for the next attempt.
+func (e *CommandExecutor) ExecuteContext(ctx context.Context, cfg *config.Config, tool *config.Tool, args map[string]interface{}) (*Result, error) {
+	args, err := coerceArguments(tool, args, cfg.Settings.Coercion == "strict")
 	if err != nil {
-		return "", fmt.Errorf("failed to build command: %w", err)
+		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
-
-	// Validate command against security policy
-	if err := e.sandbox.ValidateCommand(cmdParts, &cfg.Security); err != nil {
-		return "", fmt.Errorf("command blocked by security policy: %w", err)
+	if err := checkConstraints(tool, args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := checkConditions(tool, args); err != nil {
+		return nil, err
 	}
 
 	// Determine working directory
@@ -61,89 +129,472 @@ func (e *CommandExecutor) Execute(cfg *config.Config, tool *config.Tool, args ma
 		workingDir, _ = os.Getwd()
 	}
 
-	// Create command
+	baseDir, err := filepath.Abs(workingDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid working directory: %w", err)
+	}
+	args, err = resolvePaths(tool, args, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	// Binary output may go to a managed temp file passed as an argument.
+	// The sandbox checks the caller's arguments, without the temp file.
+	callerArgs := args
+	args, outFile, cleanup, err := prepareOutputFile(tool, args)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	secrets := sensitiveValues(tool, callerArgs)
+
+	// Build the command, or the script in shell mode
+	start := time.Now()
+	var cmdParts, env []string
+	var shellCmd *ShellCommand
+	if tool.Script != "" {
+		shellCmd, err = e.builder.BuildShellCommand(cfg, tool, args)
+		if err == nil {
+			cmdParts, env = shellCmd.Argv(), shellCmd.Env
+		}
+	} else {
+		cmdParts, err = e.builder.BuildCommand(cfg, tool, args)
+	}
+	e.traceStage(cfg, tool, "build", start, err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build command: %w", err)
+	}
+
+	// Validate command against security policy
+	start = time.Now()
+	err = e.sandbox.ValidateArguments(tool, callerArgs, &cfg.Security)
+	if err == nil && shellCmd != nil {
+		err = e.sandbox.ValidateShellCommand(shellCmd, &cfg.Security)
+	} else if err == nil {
+		err = e.sandbox.ValidateCommand(cmdParts, &cfg.Security)
+		if err == nil {
+			err = cfg.Settings.VerifyCommand()
+		}
+	}
+	e.traceStage(cfg, tool, "sandbox", start, err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("command blocked by security policy: %w", err)
+	}
+
+	// Reuse the result of an identical call while it is fresh
+	var cacheKeyValue string
+	if tool.Cache != nil {
+		start = time.Now()
+		cacheKeyValue = cacheKey(cfg, tool, workingDir, cmdParts, env)
+		cached, hit := e.cache.get(cacheKeyValue, tool, workingDir)
+		e.traceStage(cfg, tool, "cache", start, nil, map[string]interface{}{
+			"hit": hit,
+		})
+		if hit {
+			return cached, nil
+		}
+	}
+
+	// Run the command, retrying transient failures
+	started := time.Now()
+	result, err := e.run(ctx, cfg, tool, cmdParts, env, secrets, workingDir, 1)
+	for attempt := 1; err != nil && tool.Retry != nil; attempt++ {
+		delay, retry := e.retryDelay(ctx, cfg, tool, result, args, err, attempt)
+		if !retry {
+			break
+		}
+		log.Warn().
+			Err(err).
+			Str("tool", tool.Name).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("Command failed, retrying")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		result, err = e.run(ctx, cfg, tool, cmdParts, env, secrets, workingDir, attempt+1)
+	}
+
+	// The call may have changed what other tools report
+	e.cache.invalidate(cfg, tool.Name)
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return result, fmt.Errorf("call deadline exceeded: %w", ctx.Err())
+		}
+		if ctx.Err() != nil {
+			return result, fmt.Errorf("call canceled: %w", ctx.Err())
+		}
+		if errors.Is(err, ErrTimeout) {
+			return result, e.describeFailure(cfg, tool, result, args, err)
+		}
+		if !isExitError(err) {
+			return result, fmt.Errorf("command failed: %w", err)
+		}
+		if !isSuccessExitCode(tool, result.ExitCode) {
+			err = fmt.Errorf("command failed with exit code %d", result.ExitCode)
+			return result, e.describeFailure(cfg, tool, result, args, err)
+		}
+	}
+	rawStdout := result.rawStdout
+
+	// Binary output is returned as is, without transforms or parsing
+	if tool.Output.IsBinary() {
+		binary, err := readBinaryOutput(tool, rawStdout, outFile, workingDir, started, cfg.Security.MaxOutputSize)
+		attrs := map[string]interface{}{
+			"output_type": tool.Output.Type,
+		}
+		if binary != nil {
+			attrs["mime_type"] = binary.MimeType
+			attrs["output_bytes"] = len(binary.Data)
+		}
+		e.traceStage(cfg, tool, "parse", time.Now(), err, attrs)
+		if err != nil {
+			return result, err
+		}
+		result.Binary = binary
+		result.Output = fmt.Sprintf("%s output (%s, %d bytes)", tool.Output.Type, binary.MimeType, len(binary.Data))
+		if tool.Cache != nil {
+			e.cache.put(cacheKeyValue, cfg, tool, workingDir, result)
+		}
+		return result, nil
+	}
+
+	// Post-process and parse stdout according to configuration
+	start = time.Now()
+	output, err := parser.ApplyTransforms(rawStdout, tool.Output.Transform)
+	transformed := output
+	if err == nil {
+		var parsedOutput string
+		parsedOutput, err = parser.ParseOutput(output, &tool.Output)
+		if err == nil {
+			output = parsedOutput
+		}
+	}
+	attrs := map[string]interface{}{
+		"output_type": tool.Output.Type,
+	}
+	if tool.Output.Schema != nil {
+		var schemaErr error
+		output, schemaErr = e.validateOutput(tool, output, transformed, err, result)
+		attrs["schema_valid"] = result.Structured != nil
+		if schemaErr != nil {
+			e.traceStage(cfg, tool, "parse", start, schemaErr, attrs)
+			return result, schemaErr
+		}
+	} else if err != nil {
+		log.Warn().Err(err).Msg("Failed to parse output, returning raw")
+	}
+	e.traceStage(cfg, tool, "parse", start, err, attrs)
+
+	// Truncate the final output, keeping the rest for continuation
+	result.PageSize = parser.OutputLimit(tool.Output.Transform, cfg.Security.MaxOutputSize)
+	result.Output, result.Remainder = parser.Truncate(output, result.PageSize)
+	if tool.Cache != nil {
+		e.cache.put(cacheKeyValue, cfg, tool, workingDir, result)
+	}
+	return result, nil
+}
+
+// Redacted replaces sensitive argument values in what tracers see
+const Redacted = "[REDACTED]"
+
+// sensitiveValues returns the values of the call's sensitive arguments as
+// they may appear in argv or the environment
+func sensitiveValues(tool *config.Tool, args map[string]interface{}) []string {
+	var secrets []string
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case nil, bool:
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		default:
+			if s := formatScalar(v); s != "" {
+				secrets = append(secrets, s)
+			}
+		}
+	}
+	for name, value := range args {
+		if tool.IsSensitive(name) {
+			collect(value)
+		}
+	}
+	return secrets
+}
+
+// redactAll returns parts with every secret replaced by Redacted
+func redactAll(parts, secrets []string) []string {
+	if len(secrets) == 0 {
+		return parts
+	}
+	redacted := make([]string, len(parts))
+	for i, part := range parts {
+		for _, secret := range secrets {
+			part = strings.ReplaceAll(part, secret, Redacted)
+		}
+		redacted[i] = part
+	}
+	return redacted
+}
+
+// run executes the command once. The returned error is an *exec.ExitError
+// for a failure exit code, wraps ErrTimeout when the run exceeded the
+// timeout, or reports that the command could not be started.
+func (e *CommandExecutor) run(ctx context.Context, cfg *config.Config, tool *config.Tool, cmdParts, env, secrets []string, workingDir string, attempt int) (*Result, error) {
 	timeout := cfg.Settings.Timeout
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, cmdParts[0], cmdParts[1:]...)
+	cmd := exec.CommandContext(runCtx, cmdParts[0], cmdParts[1:]...)
 	cmd.Dir = workingDir
+	// Children that outlive a killed command, like a script's subprocesses,
+	// keep the output pipes open; stop waiting for them
+	cmd.WaitDelay = time.Second
 
 	// Set environment variables
 	cmd.Env = os.Environ()
 	for _, envVar := range cfg.Settings.Environment {
 		cmd.Env = append(cmd.Env, envVar)
 	}
+	cmd.Env = append(cmd.Env, env...)
 
 	// Capture output
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
+	// Logs and tracers see sensitive argument values redacted
+	traced := redactAll(cmdParts, secrets)
 	log.Debug().
-		Strs("command", cmdParts).
+		Strs("command", traced).
 		Str("workingDir", workingDir).
+		Int("attempt", attempt).
 		Msg("Executing command")
 
 	// Trace command execution
-	if e.tracer != nil {
-		e.tracer.TraceCommand(cmdParts[0], cmdParts[1:], workingDir, cmd.Env)
+	tracedEnv := redactAll(cmd.Env, secrets)
+	for _, tracer := range e.tracers {
+		tracer.TraceCommand(traced[0], traced[1:], workingDir, tracedEnv)
 	}
 
-	// Run the command
-	err = cmd.Run()
-
-	// Check for timeout
-	if ctx.Err() == context.DeadlineExceeded {
-		return "", fmt.Errorf("command timed out after %v", timeout)
-	}
+	start := time.Now()
+	err := cmd.Run()
 
-	// Get output
-	output := stdout.String()
-	if stderr.String() != "" {
-		output += "\n" + stderr.String()
+	result := &Result{
+		Stdout:    truncateOutput(stdout.String(), cfg.Security.MaxOutputSize),
+		Stderr:    truncateOutput(stderr.String(), cfg.Security.MaxOutputSize),
+		Duration:  time.Since(start),
+		Attempts:  attempt,
+		rawStdout: stdout.String(),
 	}
 
-	// Check output size limit
-	if cfg.Security.MaxOutputSize > 0 && int64(len(output)) > cfg.Security.MaxOutputSize {
-		output = output[:cfg.Security.MaxOutputSize]
-		output += "\n... (output truncated)"
+	// Check for timeout; cancellation of the whole call is not a timeout
+	if runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		result.ExitCode = -1
+		err = fmt.Errorf("%w after %v", ErrTimeout, timeout)
+		e.traceStage(cfg, tool, "exec", start, err, map[string]interface{}{
+			"exit_code": -1,
+			"attempt":   attempt,
+		})
+		return result, err
 	}
 
-	// Trace command output
-	exitCode := 0
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
+			result.ExitCode = exitErr.ExitCode()
 		} else {
-			exitCode = -1
+			result.ExitCode = -1
 		}
 	}
 
-	if e.tracer != nil {
-		e.tracer.TraceCommandOutput(output, exitCode, err)
+	// Trace command output
+	for _, tracer := range e.tracers {
+		tracer.TraceCommandOutput(result.Stdout, result.ExitCode, err)
+	}
+	attrs := map[string]interface{}{
+		"exit_code":    result.ExitCode,
+		"output_bytes": len(result.Stdout),
+		"stderr_bytes": len(result.Stderr),
+		"attempt":      attempt,
 	}
+	// The call's deadline killed the command: a timeout, though not of this run
+	if ctx.Err() == context.DeadlineExceeded {
+		attrs["call_deadline_exceeded"] = true
+	}
+	e.traceStage(cfg, tool, "exec", start, err, attrs)
 
-	// If command failed, include error info
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return output, fmt.Errorf("command failed with exit code %d", exitErr.ExitCode())
+	return result, err
+}
+
+// retryDelay decides whether a failed attempt is retried under the tool's
+// retry policy and how long to wait first. It gives up when the wait would
+// outlast the call's deadline.
+func (e *CommandExecutor) retryDelay(ctx context.Context, cfg *config.Config, tool *config.Tool, result *Result, args map[string]interface{}, err error, attempt int) (time.Duration, bool) {
+	policy := tool.Retry
+	if attempt >= policy.MaxAttempts || ctx.Err() != nil {
+		return 0, false
+	}
+
+	switch {
+	case errors.Is(err, ErrTimeout):
+		if !policy.OnTimeout {
+			return 0, false
+		}
+	case isExitError(err):
+		if isSuccessExitCode(tool, result.ExitCode) || !retryableFailure(cfg, tool, result, args) {
+			return 0, false
 		}
-		return output, fmt.Errorf("command failed: %w", err)
+	default:
+		// The command could not be started; running it again will not help
+		return 0, false
 	}
 
-	// Parse output according to configuration
-	parsedOutput, err := parser.ParseOutput(output, &tool.Output)
-	if err != nil {
-		log.Warn().Err(err).Msg("Failed to parse output, returning raw")
+	delay := policy.Delay(attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return 0, false
+	}
+	return delay, true
+}
+
+// retryableFailure reports whether a failure exit code matches the retry
+// conditions: a listed exit code, a stderr pattern, or a matching error
+// rule marked retryable. Without exit codes or patterns every failure is
+// retried.
+func retryableFailure(cfg *config.Config, tool *config.Tool, result *Result, args map[string]interface{}) bool {
+	policy := tool.Retry
+	patterns, _ := policy.StderrRegexps()
+	if len(policy.ExitCodes) == 0 && len(patterns) == 0 {
+		return true
+	}
+
+	for _, code := range policy.ExitCodes {
+		if code == result.ExitCode {
+			return true
+		}
+	}
+	for _, re := range patterns {
+		if re.MatchString(result.Stderr) {
+			return true
+		}
+	}
+	if info := matchErrorRule(cfg, tool, result, args); info != nil && info.Retryable {
+		return true
+	}
+	return false
+}
+
+func isExitError(err error) bool {
+	_, ok := err.(*exec.ExitError)
+	return ok
+}
+
+// validateOutput checks parsed output against the tool's output schema and
+// applies its on_mismatch policy. A parse failure counts as a mismatch.
+// It returns the output to use, or an error when the policy is fail.
+func (e *CommandExecutor) validateOutput(tool *config.Tool, output, raw string, parseErr error, result *Result) (string, error) {
+	mismatch := parseErr
+	if mismatch != nil {
+		mismatch = fmt.Errorf("failed to parse output: %w", mismatch)
+	} else {
+		var value interface{} = output
+		if tool.Output.Type != "raw" {
+			err := json.Unmarshal([]byte(output), &value)
+			if err != nil {
+				mismatch = fmt.Errorf("failed to decode parsed output: %w", err)
+			}
+		}
+		if mismatch == nil {
+			if err := schema.Validate(value, tool.Output.Schema); err != nil {
+				mismatch = fmt.Errorf("output does not match schema: %w", err)
+			} else {
+				result.Structured = value
+				return output, nil
+			}
+		}
+	}
+
+	switch tool.Output.OnMismatch {
+	case "fail":
+		return output, mismatch
+	case "pass":
+		log.Warn().Err(mismatch).Str("tool", tool.Name).Msg("Output schema mismatch")
 		return output, nil
+	default:
+		log.Warn().Err(mismatch).Str("tool", tool.Name).Msg("Output schema mismatch, returning raw")
+		result.Warnings = append(result.Warnings, mismatch.Error())
+		return raw, nil
+	}
+}
+
+// describeFailure applies the errors: rules to a failed command. When a rule
+// matches, its message replaces the generic error and the details are kept
+// on the result.
+func (e *CommandExecutor) describeFailure(cfg *config.Config, tool *config.Tool, result *Result, args map[string]interface{}, err error) error {
+	info := matchErrorRule(cfg, tool, result, args)
+	if info == nil {
+		return err
 	}
 
-	return parsedOutput, nil
+	result.Error = info
+	return fmt.Errorf("%s (%w)", info.Message, err)
+}
+
+// isSuccessExitCode reports whether an exit code counts as success for a tool
+func isSuccessExitCode(tool *config.Tool, exitCode int) bool {
+	if len(tool.SuccessExitCodes) == 0 {
+		return exitCode == 0
+	}
+	for _, code := range tool.SuccessExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateOutput enforces the maximum output size on captured output
+func truncateOutput(output string, maxSize int64) string {
+	if head, rest := parser.Truncate(output, int(maxSize)); rest != "" {
+		return head + "\n... (output truncated)"
+	}
+	return output
+}
+
+// traceStage reports a completed execution stage to every StageTracer
+func (e *CommandExecutor) traceStage(cfg *config.Config, tool *config.Tool, name string, start time.Time, err error, attrs map[string]interface{}) {
+	stage := Stage{
+		Name:       name,
+		Config:     cfg.Metadata.Name,
+		Tool:       tool.Name,
+		Start:      start,
+		Duration:   time.Since(start),
+		Attributes: attrs,
+		Err:        err,
+	}
+	for _, tracer := range e.tracers {
+		if st, ok := tracer.(StageTracer); ok {
+			st.TraceStage(stage)
+		}
+	}
 }
 
 // ExecuteChain executes a chain of commands
@@ -152,14 +603,17 @@ func (e *CommandExecutor) ExecuteChain(cfg *config.Config, chain []config.Chain,
 
 	for i, chainCmd := range chain {
 		// Build command with substitutions
-		cmdParts := []string{cfg.Settings.Command}
+		cmdParts := []string{cfg.Settings.Executable()}
 		if chainCmd.Command != "" {
 			cmdParts = append(cmdParts, chainCmd.Command)
 		}
 
-		// Process arguments with variable substitution
+		// Render argument templates; ${var} still works as a shorthand
 		for _, arg := range chainCmd.Arguments {
-			processed := e.substituteVariables(arg, args)
+			processed, err := template.Render(arg, args)
+			if err != nil {
+				return strings.Join(outputs, "\n"), fmt.Errorf("chain step %d: %w", i+1, err)
+			}
 			cmdParts = append(cmdParts, processed)
 		}
 
@@ -202,16 +656,6 @@ func (e *CommandExecutor) ExecuteChain(cfg *config.Config, chain []config.Chain,
 	return strings.Join(outputs, "\n"), nil
 }
 
-// substituteVariables replaces ${var} with values from args
-func (e *CommandExecutor) substituteVariables(input string, args map[string]interface{}) string {
-	result := input
-	for key, value := range args {
-		placeholder := fmt.Sprintf("${%s}", key)
-		result = strings.ReplaceAll(result, placeholder, fmt.Sprintf("%v", value))
-	}
-	return result
-}
-
 // Sandbox provides security sandboxing for commands
 type Sandbox struct{}
 
@@ -232,27 +676,52 @@ func (s *Sandbox) ValidateCommand(cmdParts []string, security *config.Security)
 		return fmt.Errorf("command '%s' is blocked", cmd)
 	}
 
-	// Check for common injection patterns (unless explicitly disabled)
-	if !security.DisableInjectionCheck {
-		for _, part := range cmdParts {
-			if pattern := s.findInjectionPattern(part); pattern != "" {
-				return fmt.Errorf("potential command injection detected\n\nPattern found: %s\nIn content: %s\n\nThis security check prevents shell injection attacks.\nIf this is a false positive (e.g., writing documentation with code examples),\nyou can disable this check by adding to your UMCP config YAML:\n\nsecurity:\n  disable_injection_check: true\n\nOnly do this for trusted tools that handle user text content.",
-					pattern, part)
-			}
+	if !security.IsCommandAllowed(cmdParts[0]) {
+		return fmt.Errorf("command '%s' is not in allowed commands", cmdParts[0])
+	}
+	if err := checkDeniedFlags(cmdParts[1:], security); err != nil {
+		return err
+	}
+	if len(security.AllowedSubcommands) > 0 {
+		subcommand := firstOperand(cmdParts[1:])
+		if subcommand == "" {
+			return fmt.Errorf("a subcommand is required")
+		}
+		if !security.IsSubcommandAllowed(subcommand) {
+			return fmt.Errorf("subcommand '%s' is not allowed", subcommand)
 		}
 	}
 
-	// Validate file paths (skip if injection check is disabled - for text content tools)
-	if !security.DisableInjectionCheck {
-		for _, part := range cmdParts[1:] {
-			if s.looksLikeFilePath(part) {
-				if !config.IsPathAllowed(part, security.AllowedPaths) {
-					return fmt.Errorf("path '%s' is not in allowed paths", part)
-				}
+	// Argument values are sanitized and checked against allowed paths by
+	// ValidateArguments
+	return nil
+}
+
+// firstOperand returns the first argument that is not a flag, which is
+// taken to be the subcommand
+func firstOperand(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			if i+1 < len(args) {
+				return args[i+1]
 			}
+			return ""
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return arg
 		}
 	}
+	return ""
+}
 
+// checkDeniedFlags reports the first argument that matches a denied flag
+// pattern
+func checkDeniedFlags(args []string, security *config.Security) error {
+	for _, arg := range args {
+		if pattern := security.DeniedFlag(arg); pattern != "" {
+			return fmt.Errorf("argument '%s' matches denied flag '%s'", arg, pattern)
+		}
+	}
 	return nil
 }
 
@@ -279,6 +748,10 @@ func (s *Sandbox) hasInjectionPattern(input string) bool {
 
 // looksLikeFilePath checks if a string looks like a file path
 func (s *Sandbox) looksLikeFilePath(input string) bool {
+	// URLs contain slashes but are not local paths
+	if strings.Contains(input, "://") {
+		return false
+	}
 	// Simple heuristic - starts with / or ./ or contains /
 	return strings.HasPrefix(input, "/") ||
 		strings.HasPrefix(input, "./") ||
@@ -0,0 +1,401 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shellTool returns a config and tool that run a script with sh -c
+func shellTool(output config.Output) (*config.Config, *config.Tool) {
+	cfg := &config.Config{
+		Metadata: config.Metadata{Name: "test"},
+		Settings: config.Settings{Command: "sh", WorkingDir: "."},
+		Security: config.Security{DisableInjectionCheck: true},
+	}
+	tool := &config.Tool{
+		Name:    "script",
+		Command: "-c",
+		Arguments: []config.Argument{
+			{Name: "script", Type: "string", Positional: true, Required: true},
+		},
+		Output: output,
+	}
+	return cfg, tool
+}
+
+func TestExecuteSeparatesStdoutAndStderr(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "json"})
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `echo '{"ok": true}'; echo 'warning: deprecated' >&2`,
+	})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"ok": true}`, result.Output)
+	assert.Equal(t, "warning: deprecated\n", result.Stderr)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Greater(t, result.Duration.Nanoseconds(), int64(0))
+}
+
+func TestExecuteFailureKeepsOutput(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `echo partial; echo 'fatal: broken' >&2; exit 3`,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit code 3")
+
+	require.NotNil(t, result)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "partial\n", result.Stdout)
+	assert.Equal(t, "fatal: broken\n", result.Stderr)
+}
+
+func TestExecuteSuccessExitCodes(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "lines"})
+	tool.SuccessExitCodes = []int{0, 1}
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `echo a; echo b; exit 1`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.JSONEq(t, `["a", "b"]`, result.Output)
+
+	_, err = executor.Execute(cfg, tool, map[string]interface{}{"script": `exit 2`})
+	assert.Error(t, err)
+}
+
+func TestExecuteErrorRules(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	code128 := 128
+	tool.Errors = []config.ErrorRule{
+		{
+			ExitCode: &code128,
+			Stderr:   `not a git repository`,
+			Message:  "not a git repository — pass a repo path",
+			Hint:     "Set the path argument to a directory inside a git repository",
+		},
+	}
+	cfg.Errors = []config.ErrorRule{
+		{
+			Stderr:    `Could not resolve host: (?P<host>\S+)`,
+			Message:   "network error reaching ${host} (exit ${exit_code})",
+			Retryable: true,
+		},
+	}
+	executor := NewCommandExecutor()
+
+	tests := []struct {
+		name      string
+		script    string
+		expected  *ErrorInfo
+		errSubstr string
+	}{
+		{
+			name:   "tool rule",
+			script: `echo 'fatal: not a git repository (or any parent)' >&2; exit 128`,
+			expected: &ErrorInfo{
+				Message: "not a git repository — pass a repo path",
+				Hint:    "Set the path argument to a directory inside a git repository",
+			},
+			errSubstr: "not a git repository — pass a repo path (command failed with exit code 128)",
+		},
+		{
+			name:   "config rule with named group",
+			script: `echo 'fatal: Could not resolve host: github.com' >&2; exit 1`,
+			expected: &ErrorInfo{
+				Message:   "network error reaching github.com (exit 1)",
+				Retryable: true,
+			},
+			errSubstr: "network error reaching github.com",
+		},
+		{
+			name:      "exit code without matching stderr",
+			script:    `echo 'other' >&2; exit 128`,
+			expected:  nil,
+			errSubstr: "command failed with exit code 128",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executor.Execute(cfg, tool, map[string]interface{}{"script": tt.script})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errSubstr)
+			assert.Equal(t, tt.expected, result.Error)
+		})
+	}
+}
+
+func TestExecuteTransformAndTruncate(t *testing.T) {
+	cfg, tool := shellTool(config.Output{
+		Type: "raw",
+		Transform: []config.TransformStep{
+			{StripANSI: true},
+			{Exclude: `^debug`},
+			{MaxTokens: 3},
+		},
+	})
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"script": `printf '\033[1mone\033[0m\ndebug x\ntwo\nthree\nfour\n'`,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "one\ntwo\n", result.Output)
+	assert.Equal(t, "three\nfour\n", result.Remainder)
+	assert.Equal(t, 12, result.PageSize)
+}
+
+func TestExecuteOutputSchema(t *testing.T) {
+	outputSchema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"ok"},
+		"properties": map[string]interface{}{
+			"ok": map[string]interface{}{"type": "boolean"},
+		},
+	}
+	executor := NewCommandExecutor()
+
+	tests := []struct {
+		name       string
+		script     string
+		onMismatch string
+		wantErr    string
+		output     string
+		warnings   int
+		structured bool
+	}{
+		{name: "valid", script: `echo '{"ok": true}'`, output: "{\n  \"ok\": true\n}", structured: true},
+		{name: "warn returns raw", script: `echo '{"ok": "yes"}'`, output: "{\"ok\": \"yes\"}\n", warnings: 1},
+		{name: "parse failure warns", script: `echo 'not json'`, output: "not json\n", warnings: 1},
+		{name: "pass keeps parsed", script: `echo '{"ok": "yes"}'`, onMismatch: "pass", output: "{\n  \"ok\": \"yes\"\n}"},
+		{name: "fail", script: `echo '{}'`, onMismatch: "fail", wantErr: `output does not match schema: $: missing required property "ok"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, tool := shellTool(config.Output{Type: "json", Schema: outputSchema, OnMismatch: tt.onMismatch})
+
+			result, err := executor.Execute(cfg, tool, map[string]interface{}{"script": tt.script})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.output, result.Output)
+			assert.Len(t, result.Warnings, tt.warnings)
+			assert.Equal(t, tt.structured, result.Structured != nil)
+		})
+	}
+}
+
+func TestExecuteRetry(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "runs")
+	// Fails with exit code 75 and a stderr message until the third run
+	script := fmt.Sprintf(`echo run >> %s; n=$(wc -l < %s); [ $n -ge 3 ] && echo ok && exit 0; echo "connection reset" >&2; exit 75`, counter, counter)
+
+	tests := []struct {
+		name     string
+		retry    config.Retry
+		attempts int
+		success  bool
+	}{
+		{
+			name:     "any failure",
+			retry:    config.Retry{MaxAttempts: 5},
+			attempts: 3,
+			success:  true,
+		},
+		{
+			name:     "matching exit code",
+			retry:    config.Retry{MaxAttempts: 5, ExitCodes: []int{75}},
+			attempts: 3,
+			success:  true,
+		},
+		{
+			name:     "matching stderr",
+			retry:    config.Retry{MaxAttempts: 5, ExitCodes: []int{1}, Stderr: []string{"connection (reset|refused)"}},
+			attempts: 3,
+			success:  true,
+		},
+		{
+			name:     "no matching condition",
+			retry:    config.Retry{MaxAttempts: 5, ExitCodes: []int{1}},
+			attempts: 1,
+		},
+		{
+			name:     "attempts exhausted",
+			retry:    config.Retry{MaxAttempts: 2},
+			attempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.RemoveAll(counter))
+			cfg, tool := shellTool(config.Output{Type: "raw"})
+			tt.retry.Backoff = time.Millisecond
+			tt.retry.Multiplier = 1
+			tool.Retry = &tt.retry
+			executor := NewCommandExecutor()
+			recorder := &stageRecorder{}
+			executor.SetTracer(recorder)
+
+			result, err := executor.Execute(cfg, tool, map[string]interface{}{"script": script})
+			require.NotNil(t, result)
+			assert.Equal(t, tt.attempts, result.Attempts)
+			if tt.success {
+				require.NoError(t, err)
+				assert.Equal(t, "ok\n", result.Output)
+			} else {
+				require.Error(t, err)
+				assert.Equal(t, 75, result.ExitCode)
+			}
+
+			var attempts []interface{}
+			for _, stage := range recorder.stages {
+				if stage.Name == "exec" {
+					attempts = append(attempts, stage.Attributes["attempt"])
+				}
+			}
+			assert.Len(t, attempts, tt.attempts)
+			assert.Equal(t, tt.attempts, attempts[len(attempts)-1])
+		})
+	}
+}
+
+func TestExecuteRetryTimeout(t *testing.T) {
+	for _, onTimeout := range []bool{false, true} {
+		cfg, tool := shellTool(config.Output{Type: "raw"})
+		cfg.Settings.Timeout = 50 * time.Millisecond
+		tool.Retry = &config.Retry{MaxAttempts: 2, Backoff: time.Millisecond, Multiplier: 1, OnTimeout: onTimeout}
+		executor := NewCommandExecutor()
+
+		result, err := executor.Execute(cfg, tool, map[string]interface{}{"script": "exec sleep 1"})
+		require.ErrorIs(t, err, ErrTimeout)
+		if onTimeout {
+			assert.Equal(t, 2, result.Attempts)
+		} else {
+			assert.Equal(t, 1, result.Attempts)
+		}
+	}
+}
+
+func TestExecuteRetryRespectsContext(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	tool.Retry = &config.Retry{MaxAttempts: 3, Backoff: time.Second, Multiplier: 1}
+	executor := NewCommandExecutor()
+	args := map[string]interface{}{"script": "exit 1"}
+
+	// The backoff would outlast the deadline, so there is no second attempt
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	result, err := executor.ExecuteContext(ctx, cfg, tool, args)
+	require.Error(t, err)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Less(t, time.Since(started), time.Second)
+
+	// Cancellation interrupts the backoff
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	started = time.Now()
+	_, err = executor.ExecuteContext(ctx, cfg, tool, args)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(started), time.Second)
+
+	// The deadline also ends a run in progress, which is traced as such
+	recorder := &stageRecorder{}
+	executor.SetTracer(recorder)
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started = time.Now()
+	_, err = executor.ExecuteContext(ctx, cfg, tool, map[string]interface{}{"script": "exec sleep 5"})
+	assert.ErrorContains(t, err, "call deadline exceeded")
+	assert.Less(t, time.Since(started), time.Second)
+	exec := recorder.stages[len(recorder.stages)-1]
+	assert.Equal(t, "exec", exec.Name)
+	assert.Equal(t, true, exec.Attributes["call_deadline_exceeded"])
+}
+
+func TestExecuteRedactsSensitiveArgumentsInTraces(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	tool.Arguments = append(tool.Arguments,
+		config.Argument{Name: "shell", Type: "string", Positional: true, Default: "sh"},
+		config.Argument{Name: "pin", Type: "string", Positional: true, Sensitive: true},
+		config.Argument{Name: "api_key", Type: "string", Flag: "--key"},
+	)
+	executor := NewCommandExecutor()
+	recorder := &stageRecorder{}
+	executor.SetTracer(recorder)
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"script":  `echo "$1"`,
+		"pin":     "4711",
+		"api_key": "k3y",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "4711\n", result.Output)
+
+	assert.Equal(t, []string{"sh", "-c", `echo "$1"`, "sh", Redacted, "--key", Redacted}, recorder.argv)
+	for _, v := range recorder.env {
+		assert.NotContains(t, v, "k3y")
+	}
+}
+
+func TestValidateCommandAllowlist(t *testing.T) {
+	sandbox := NewSandbox()
+	security := &config.Security{
+		AllowedCommands:    []string{"git"},
+		AllowedSubcommands: []string{"log", "status"},
+		DeniedFlags:        []string{"--exec", "-c", "--upload-pack"},
+	}
+	if _, err := config.ResolveCommand("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tests := []struct {
+		name    string
+		argv    []string
+		wantErr string
+	}{
+		{name: "allowed", argv: []string{"git", "log", "--oneline", "-n", "5"}},
+		{name: "flags before the subcommand", argv: []string{"git", "--no-pager", "status"}},
+		{name: "command not allowed", argv: []string{"sh", "-x"}, wantErr: "command 'sh' is not in allowed commands"},
+		{name: "subcommand not allowed", argv: []string{"git", "push"}, wantErr: "subcommand 'push' is not allowed"},
+		{name: "no subcommand", argv: []string{"git", "--version"}, wantErr: "a subcommand is required"},
+		{name: "denied flag", argv: []string{"git", "-c", "core.pager=sh", "log"}, wantErr: "argument '-c' matches denied flag '-c'"},
+		{
+			name:    "denied flag with a value",
+			argv:    []string{"git", "log", "--upload-pack=/tmp/evil"},
+			wantErr: "argument '--upload-pack=/tmp/evil' matches denied flag '--upload-pack'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sandbox.ValidateCommand(tt.argv, security)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charignon/umcp/internal/config"
+)
+
+// resolvePaths canonicalizes the values of path arguments, defaults
+// included: ~ is expanded, relative paths are joined to the working
+// directory and symlinks are evaluated. The command receives the resolved
+// path, so what was checked is what runs. must_exist, kind and access are
+// checked here; allowed_paths is checked by the sandbox.
+func resolvePaths(tool *config.Tool, args map[string]interface{}, workingDir string) (map[string]interface{}, error) {
+	var resolved map[string]interface{}
+	for _, arg := range tool.Arguments {
+		if !arg.IsPath() {
+			continue
+		}
+		value, exists := args[arg.Name]
+		if !exists {
+			value = arg.Default
+		}
+		if value == nil {
+			continue
+		}
+
+		if resolved == nil {
+			resolved = make(map[string]interface{}, len(args))
+			for key, v := range args {
+				resolved[key] = v
+			}
+		}
+
+		if arg.Type == "path" {
+			path, err := resolvePath(arg, value, workingDir)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", arg.Name, err)
+			}
+			resolved[arg.Name] = path
+			continue
+		}
+
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value}
+		}
+		paths := make([]interface{}, len(items))
+		for i, item := range items {
+			path, err := resolvePath(arg, item, workingDir)
+			if err != nil {
+				return nil, fmt.Errorf("%s: item %d: %w", arg.Name, i, err)
+			}
+			paths[i] = path
+		}
+		resolved[arg.Name] = paths
+	}
+
+	if resolved == nil {
+		return args, nil
+	}
+	return resolved, nil
+}
+
+// resolvePath resolves one path value and checks it against the argument's
+// must_exist, kind and access settings
+func resolvePath(arg config.Argument, value interface{}, workingDir string) (string, error) {
+	path, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a path, got %T", value)
+	}
+	if path == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot expand %s: %w", path, err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+
+	path, err := evalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if arg.MustExist {
+			return "", fmt.Errorf("path %s does not exist", path)
+		}
+		if arg.Access == "write" {
+			// A new file needs a writable directory
+			if err := checkAccess(filepath.Dir(path), true, "write"); err != nil {
+				return "", err
+			}
+		}
+		return path, nil
+	case err != nil:
+		return "", err
+	}
+
+	if arg.Kind == "file" && info.IsDir() {
+		return "", fmt.Errorf("path %s is a directory, not a file", path)
+	}
+	if arg.Kind == "dir" && !info.IsDir() {
+		return "", fmt.Errorf("path %s is not a directory", path)
+	}
+	if arg.Access != "" {
+		if err := checkAccess(path, info.IsDir(), arg.Access); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// evalSymlinks evaluates symlinks in the longest existing prefix of path,
+// so paths that do not exist yet are still canonical. Dangling symlinks
+// are followed to where their target would be.
+func evalSymlinks(path string) (string, error) {
+	rest := ""
+	for links := 0; ; {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			if links++; links > 40 {
+				return "", fmt.Errorf("too many links in %s", path)
+			}
+			target, err := os.Readlink(path)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			path = filepath.Clean(target)
+			continue
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(path, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
+
+// checkAccess checks that the process can read or write a path by trying
+// it: files are opened, directories listed or written a temporary file
+func checkAccess(path string, isDir bool, access string) error {
+	var err error
+	switch {
+	case access == "read":
+		var f *os.File
+		if f, err = os.Open(path); err == nil {
+			if isDir {
+				if _, err = f.Readdirnames(1); err == io.EOF {
+					err = nil
+				}
+			}
+			f.Close()
+		}
+	case isDir:
+		var f *os.File
+		if f, err = os.CreateTemp(path, ".umcp-access-*"); err == nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	default:
+		var f *os.File
+		if f, err = os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+			f.Close()
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	if access == "write" {
+		return fmt.Errorf("path %s is not writable", path)
+	}
+	return fmt.Errorf("path %s is not readable", path)
+}
+
+// ValidateArguments checks the caller's argument values: each, including
+// object keys and values nested in lists and objects, is sanitized as its
+// argument's sanitize mode says, then string values are checked against
+// allowed_paths. Path arguments are always checked; other string values
+// are checked when they look like file paths, unless check_path is false.
+// Errors name the argument.
+func (s *Sandbox) ValidateArguments(tool *config.Tool, args map[string]interface{}, security *config.Security) error {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		arg := tool.Argument(name)
+		if arg == nil {
+			arg = &config.Argument{Name: name}
+		}
+		mode := arg.SanitizeMode(security)
+		keys, values := argumentStrings(args[name])
+		for _, key := range keys {
+			if err := s.sanitize(mode, key); err != nil {
+				return fmt.Errorf("argument %s: key %q: %w", name, key, err)
+			}
+		}
+		for _, value := range values {
+			if err := s.sanitize(mode, value); err != nil {
+				return fmt.Errorf("argument %s: %w", name, err)
+			}
+			if !arg.ChecksPaths() || (!arg.IsPath() && !s.looksLikeFilePath(value)) {
+				continue
+			}
+			if !config.IsPathAllowed(value, security.AllowedPaths) {
+				return fmt.Errorf("argument %s: path '%s' is not in allowed paths", name, value)
+			}
+		}
+	}
+	return nil
+}
+
+// argumentStrings returns the object keys and the string values in an
+// argument value, descending into lists and objects. Keys of objects
+// passed as KEY<pairs>VALUE items reach argv as much as their values do.
+func argumentStrings(value interface{}) (keys, values []string) {
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			values = append(values, v)
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			names := make([]string, 0, len(v))
+			for key := range v {
+				names = append(names, key)
+			}
+			sort.Strings(names)
+			for _, key := range names {
+				keys = append(keys, key)
+				walk(v[key])
+			}
+		}
+	}
+	walk(value)
+	return keys, values
+}
@@ -0,0 +1,170 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePaths(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), nil, 0644))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "src"), filepath.Join(dir, "link")))
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	tool := &config.Tool{
+		Arguments: []config.Argument{
+			{Name: "file", Type: "path"},
+			{Name: "dir", Type: "path", Default: "."},
+			{Name: "out", Type: "path"},
+			{Name: "files", Type: "array", Items: &config.Value{Type: "path"}},
+			{Name: "pattern", Type: "string"},
+		},
+	}
+
+	args, err := resolvePaths(tool, map[string]interface{}{
+		"file":    "link/main.go",
+		"out":     "~/new/report.txt",
+		"files":   []interface{}{"src/../src/main.go", dir + "/missing/x"},
+		"pattern": "a/b",
+	}, dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"file":    filepath.Join(dir, "src", "main.go"),
+		"dir":     dir,
+		"out":     filepath.Join(home, "new", "report.txt"),
+		"files":   []interface{}{filepath.Join(dir, "src", "main.go"), filepath.Join(dir, "missing", "x")},
+		"pattern": "a/b",
+	}, args)
+}
+
+func TestResolvePathsChecks(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), nil, 0644))
+
+	tests := []struct {
+		name    string
+		arg     config.Argument
+		value   string
+		wantErr string
+	}{
+		{name: "exists", arg: config.Argument{MustExist: true, Kind: "file", Access: "read"}, value: "file.txt"},
+		{name: "writable directory", arg: config.Argument{Kind: "dir", Access: "write"}, value: "."},
+		{name: "new file in writable directory", arg: config.Argument{Access: "write"}, value: "new.txt"},
+		{
+			name:    "missing",
+			arg:     config.Argument{MustExist: true},
+			value:   "nope.txt",
+			wantErr: "p: path " + filepath.Join(dir, "nope.txt") + " does not exist",
+		},
+		{
+			name:    "file expected",
+			arg:     config.Argument{Kind: "file"},
+			value:   ".",
+			wantErr: "p: path " + dir + " is a directory, not a file",
+		},
+		{
+			name:    "directory expected",
+			arg:     config.Argument{Kind: "dir"},
+			value:   "file.txt",
+			wantErr: "p: path " + filepath.Join(dir, "file.txt") + " is not a directory",
+		},
+		{
+			name:    "new file in missing directory",
+			arg:     config.Argument{Access: "write"},
+			value:   "missing/new.txt",
+			wantErr: "p: path " + filepath.Join(dir, "missing") + " is not writable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.arg.Name, tt.arg.Type = "p", "path"
+			tool := &config.Tool{Arguments: []config.Argument{tt.arg}}
+			_, err := resolvePaths(tool, map[string]interface{}{"p": tt.value}, dir)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateArguments(t *testing.T) {
+	sandbox := NewSandbox()
+	tool := &config.Tool{
+		Arguments: []config.Argument{
+			{Name: "file", Type: "path"},
+			{Name: "pattern", Type: "string"},
+			{Name: "files", Type: "array", Items: &config.Value{Type: "path"}},
+		},
+	}
+	security := &config.Security{AllowedPaths: []string{"/tmp"}}
+
+	assert.NoError(t, sandbox.ValidateArguments(tool, map[string]interface{}{
+		"file":    "/tmp/x",
+		"pattern": "https://example.com/a/b",
+	}, security))
+
+	err := sandbox.ValidateArguments(tool, map[string]interface{}{"file": "/tmpevil/x"}, security)
+	assert.EqualError(t, err, "argument file: path '/tmpevil/x' is not in allowed paths")
+
+	err = sandbox.ValidateArguments(tool, map[string]interface{}{"files": []interface{}{"/tmp/a", "/etc/passwd"}}, security)
+	assert.EqualError(t, err, "argument files: path '/etc/passwd' is not in allowed paths")
+
+	err = sandbox.ValidateArguments(tool, map[string]interface{}{"pattern": "/etc/*.conf"}, security)
+	assert.EqualError(t, err, "argument pattern: path '/etc/*.conf' is not in allowed paths")
+
+	// Disabling the injection check does not skip path checks; check_path does
+	security.DisableInjectionCheck = true
+	err = sandbox.ValidateArguments(tool, map[string]interface{}{"pattern": "/etc/*.conf"}, security)
+	assert.EqualError(t, err, "argument pattern: path '/etc/*.conf' is not in allowed paths")
+	checkPath := false
+	tool.Arguments[1].CheckPath = &checkPath
+	assert.NoError(t, sandbox.ValidateArguments(tool, map[string]interface{}{"pattern": "/etc/*.conf"}, security))
+	err = sandbox.ValidateArguments(tool, map[string]interface{}{"file": "/etc/passwd"}, security)
+	assert.EqualError(t, err, "argument file: path '/etc/passwd' is not in allowed paths")
+}
+
+func TestExecuteResolvesPaths(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hi\n"), 0644))
+
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	cfg.Settings.WorkingDir = dir
+	cfg.Security.AllowedPaths = []string{dir}
+	tool.Arguments = append(tool.Arguments,
+		config.Argument{Name: "file", Type: "path", Positional: true, Position: 1, MustExist: true},
+	)
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{"script": `echo "$0"; cat "$0"`, "file": "notes.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "notes.txt")+"\nhi\n", result.Output)
+
+	outside, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), nil, 0644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "escape")))
+	_, err = executor.Execute(cfg, tool, map[string]interface{}{"script": `cat "$0"`, "file": "escape"})
+	assert.EqualError(t, err, "command blocked by security policy: argument file: path '"+
+		filepath.Join(outside, "secret")+"' is not in allowed paths")
+
+	// A dangling link resolves to where its target would be
+	tool.Arguments[len(tool.Arguments)-1].MustExist = false
+	require.NoError(t, os.Symlink(filepath.Join(outside, "new", "file"), filepath.Join(dir, "dangling")))
+	_, err = executor.Execute(cfg, tool, map[string]interface{}{"script": `echo x > "$0"`, "file": "dangling"})
+	assert.EqualError(t, err, "command blocked by security policy: argument file: path '"+
+		filepath.Join(outside, "new", "file")+"' is not in allowed paths")
+}
@@ -0,0 +1,21 @@
+package executor
+
+import "fmt"
+
+// sanitize checks one value of an argument. With flag the value must not
+// start with -, so it cannot be taken as an option (option injection).
+// With shell it must also be free of shell metacharacters.
+func (s *Sandbox) sanitize(mode, value string) error {
+	if mode == "none" {
+		return nil
+	}
+	if len(value) > 0 && value[0] == '-' {
+		return fmt.Errorf("value %q starts with '-' and could be taken as an option\n\nIf the argument may start with a dash, set sanitize: none on it in your UMCP config YAML.", value)
+	}
+	if mode == "shell" {
+		if pattern := s.findInjectionPattern(value); pattern != "" {
+			return fmt.Errorf("potential command injection detected: value contains %q\n\nIf the argument takes free text, such as a commit message, set sanitize: flag or sanitize: none on it in your UMCP config YAML.", pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateArgumentsSanitize(t *testing.T) {
+	sandbox := NewSandbox()
+	tool := &config.Tool{
+		Arguments: []config.Argument{
+			{Name: "message", Type: "string", Sanitize: "none"},
+			{Name: "question", Type: "string", Sanitize: "flag"},
+			{Name: "branch", Type: "string"},
+			{Name: "tags", Type: "array"},
+			{Name: "env", Type: "object", Pairs: "="},
+			{Name: "mounts", Type: "array", Items: &config.Value{Type: "object"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		disable bool
+		wantErr string
+	}{
+		{name: "free text", args: map[string]interface{}{"message": "-fix: a | b; c > d\nmore"}},
+		{name: "flag mode allows metacharacters", args: map[string]interface{}{"question": "a; b | c?"}},
+		{
+			name:    "flag mode rejects a leading dash",
+			args:    map[string]interface{}{"question": "--output=x"},
+			wantErr: `argument question: value "--output=x" starts with '-' and could be taken as an option`,
+		},
+		{
+			name:    "shell mode by default",
+			args:    map[string]interface{}{"branch": "main; rm -rf ~"},
+			wantErr: `argument branch: potential command injection detected: value contains ";"`,
+		},
+		{
+			name:    "shell mode rejects a leading dash",
+			args:    map[string]interface{}{"branch": "-D"},
+			wantErr: `argument branch: value "-D" starts with '-'`,
+		},
+		{
+			name:    "array items",
+			args:    map[string]interface{}{"tags": []interface{}{"ok", "`id`"}},
+			wantErr: "argument tags: potential command injection detected: value contains \"`\"",
+		},
+		{name: "object keys and values", args: map[string]interface{}{"env": map[string]interface{}{"MODE": "prod"}}},
+		{
+			name:    "object keys",
+			args:    map[string]interface{}{"env": map[string]interface{}{"A;id": "x"}},
+			wantErr: `argument env: key "A;id": potential command injection detected: value contains ";"`,
+		},
+		{
+			name:    "object key with a leading dash",
+			args:    map[string]interface{}{"env": map[string]interface{}{"--privileged": "x"}},
+			wantErr: `argument env: key "--privileged": value "--privileged" starts with '-'`,
+		},
+		{
+			name: "nested objects",
+			args: map[string]interface{}{"mounts": []interface{}{
+				map[string]interface{}{"source": "data", "target": "$(id)"},
+			}},
+			wantErr: `argument mounts: potential command injection detected: value contains "$("`,
+		},
+		{name: "disabled injection check", args: map[string]interface{}{"branch": "a|b"}, disable: true},
+		{
+			name:    "explicit mode overrides the disabled check",
+			args:    map[string]interface{}{"question": "-x"},
+			disable: true,
+			wantErr: `argument question: value "-x" starts with '-'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			security := &config.Security{DisableInjectionCheck: tt.disable}
+			err := sandbox.ValidateArguments(tool, tt.args, security)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteSanitizesArguments(t *testing.T) {
+	cfg, tool := shellTool(config.Output{Type: "raw"})
+	cfg.Security.DisableInjectionCheck = false
+	tool.Arguments[0].Sanitize = "none"
+	tool.Arguments = append(tool.Arguments, config.Argument{Name: "name", Type: "string", Positional: true, Position: 1})
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{"script": `echo "$0" | tr a-z A-Z`, "name": "umcp"})
+	assert.NoError(t, err)
+	assert.Equal(t, "UMCP\n", result.Output)
+
+	_, err = executor.Execute(cfg, tool, map[string]interface{}{"script": `echo "$0"`, "name": "-n"})
+	assert.ErrorContains(t, err, `command blocked by security policy: argument name: value "-n" starts with '-'`)
+}
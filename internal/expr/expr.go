@@ -0,0 +1,704 @@
+// Package expr parses and evaluates the conditions used by `when`.
+//
+// A condition compares argument values, written ${name}, with literals:
+//
+//	${mode} == production && ${limit} > 10
+//	not exists(branch) || ${branch} =~ "^release/"
+//	${format} in [json, "pretty json"]
+//
+// Literals are numbers, true, false, null, quoted strings and bare words,
+// which are strings. Comparisons are typed: numbers compare numerically and
+// a string operand is converted when it holds a number or boolean. The
+// logical operators are and, or and not (or &&, || and !), and a bare value
+// is true unless it is false, null, zero, empty or an empty list.
+package expr
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expr is a parsed condition
+type Expr struct {
+	text string
+	root node
+	vars []string
+}
+
+// Parse parses a condition
+func Parse(text string) (*Expr, error) {
+	tokens, err := lex(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", text, err)
+	}
+	p := &parser{tokens: tokens, seen: map[string]bool{}}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.errorf(p.peek(), "unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", text, err)
+	}
+	return &Expr{text: text, root: root, vars: p.vars}, nil
+}
+
+// String returns the condition as written
+func (e *Expr) String() string {
+	return e.text
+}
+
+// Variables returns the argument names the condition refers to
+func (e *Expr) Variables() []string {
+	return e.vars
+}
+
+// Eval evaluates the condition. Variables missing from vars are null.
+func (e *Expr) Eval(vars map[string]interface{}) (bool, error) {
+	value, err := e.root.eval(vars)
+	if err != nil {
+		return false, fmt.Errorf("condition %q: %w", e.text, err)
+	}
+	return truthy(value), nil
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokVar
+	tokString
+	tokWord
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string // Variable name, string contents, word or operator
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of condition"
+	case tokVar:
+		return "${" + t.text + "}"
+	default:
+		return strconv.Quote(t.text)
+	}
+}
+
+// operators, longest first
+var operators = []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func lex(text string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case strings.HasPrefix(text[i:], "${"):
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ${ at column %d", i+1)
+			}
+			name := text[i+2 : i+end]
+			if !isName(name) {
+				return nil, fmt.Errorf("invalid variable ${%s} at column %d", name, i+1)
+			}
+			tokens = append(tokens, token{kind: tokVar, text: name, pos: i})
+			i += end + 1
+
+		case c == '"' || c == '\'':
+			value, n, err := lexString(text[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%v at column %d", err, i+1)
+			}
+			tokens = append(tokens, token{kind: tokString, text: value, pos: i})
+			i += n
+
+		default:
+			if op := lexOperator(text[i:]); op != "" {
+				tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+				i += len(op)
+				continue
+			}
+			start := i
+			for i < len(text) && !strings.ContainsRune(" \t\n\r\"'()[],=!<>&|", rune(text[i])) {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("unexpected %q at column %d", text[i], i+1)
+			}
+			tokens = append(tokens, token{kind: tokWord, text: text[start:i], pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(text)}), nil
+}
+
+func lexOperator(text string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(text, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// lexString reads a quoted string. Double-quoted strings use Go escapes;
+// single-quoted strings are taken literally.
+func lexString(text string) (string, int, error) {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			if quote == '\'' {
+				return text[1:i], i + 1, nil
+			}
+			value, err := strconv.Unquote(text[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string %s", text[:i+1])
+			}
+			return value, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+func isName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && r != '-' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Parser
+
+type parser struct {
+	tokens []token
+	pos    int
+	vars   []string
+	seen   map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or
+// keywords
+func (p *parser) accept(texts ...string) bool {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokWord {
+		return false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf(p.peek(), "expected %q, found %s", text, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("%s at column %d", fmt.Sprintf(format, args...), t.pos+1)
+}
+
+func (p *parser) variable(name string) {
+	if !p.seen[name] {
+		p.seen[name] = true
+		p.vars = append(p.vars, name)
+	}
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||", "or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&", "and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!", "not") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch {
+	case p.accept("==", "!=", "<", "<=", ">", ">="):
+		right, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: t.text, left: left, right: right}, nil
+
+	case p.accept("=~", "!~"):
+		pattern := p.next()
+		if pattern.kind != tokString && pattern.kind != tokWord {
+			return nil, p.errorf(pattern, "expected a pattern after %s, found %s", t.text, pattern)
+		}
+		re, err := regexp.Compile(pattern.text)
+		if err != nil {
+			return nil, p.errorf(pattern, "invalid pattern: %v", err)
+		}
+		return &matchNode{negate: t.text == "!~", operand: left, re: re}, nil
+
+	case p.accept("in"):
+		return p.parseIn(left, false)
+
+	case t.kind == tokWord && t.text == "not" && p.tokens[p.pos+1].text == "in":
+		p.pos += 2
+		return p.parseIn(left, true)
+	}
+	return left, nil
+}
+
+func (p *parser) parseIn(left node, negate bool) (node, error) {
+	if !p.accept("[") {
+		list, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return &inNode{negate: negate, operand: left, list: list}, nil
+	}
+
+	var items []node
+	for !p.accept("]") {
+		if len(items) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return &inNode{negate: negate, operand: left, items: items}, nil
+}
+
+// parsePrimary parses a value, exists(name) or a parenthesized condition
+func (p *parser) parsePrimary() (node, error) {
+	t := p.peek()
+	if p.accept("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+
+	if t.kind == tokWord && t.text == "exists" && p.tokens[p.pos+1].text == "(" {
+		p.pos += 2
+		name := p.next()
+		if (name.kind != tokWord || !isName(name.text)) && name.kind != tokVar {
+			return nil, p.errorf(name, "exists expects an argument name, found %s", name)
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		p.variable(name.text)
+		return &existsNode{name: name.text}, nil
+	}
+
+	return p.parseValue()
+}
+
+// parseValue parses a variable or literal
+func (p *parser) parseValue() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokVar:
+		p.variable(t.text)
+		return &varNode{name: t.text}, nil
+	case tokString:
+		return &literalNode{value: t.text}, nil
+	case tokWord:
+		switch t.text {
+		case "and", "or", "not", "in":
+			return nil, p.errorf(t, "expected a value, found %s (quote it to use it as a string)", t)
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if f, err := strconv.ParseFloat(t.text, 64); err == nil {
+			return &literalNode{value: f}, nil
+		}
+		return &literalNode{value: t.text}, nil
+	}
+	return nil, p.errorf(t, "expected a value, found %s", t)
+}
+
+// Evaluation
+
+type node interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+type varNode struct{ name string }
+
+type existsNode struct{ name string }
+
+type notNode struct{ operand node }
+
+type logicNode struct {
+	or          bool
+	left, right node
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+type matchNode struct {
+	negate  bool
+	operand node
+	re      *regexp.Regexp
+}
+
+type inNode struct {
+	negate  bool
+	operand node
+	items   []node // A literal list
+	list    node   // Or a value holding a list
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *varNode) eval(vars map[string]interface{}) (interface{}, error) {
+	return vars[n.name], nil
+}
+
+func (n *existsNode) eval(vars map[string]interface{}) (interface{}, error) {
+	return vars[n.name] != nil, nil
+}
+
+func (n *notNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(value), nil
+}
+
+func (n *logicNode) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(left) == n.or {
+		return n.or, nil
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(right), nil
+}
+
+func (n *compareNode) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	// Ordering with a missing value is false rather than an error, since
+	// optional arguments are often omitted
+	if left == nil || right == nil {
+		return false, nil
+	}
+	cmp, err := compare(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+func (n *matchNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return n.negate, nil
+	}
+	return n.re.MatchString(toString(value)) != n.negate, nil
+}
+
+func (n *inNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	if n.list != nil {
+		list, err := n.list.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		switch l := list.(type) {
+		case nil:
+		case []interface{}:
+			items = l
+		case []string:
+			for _, s := range l {
+				items = append(items, s)
+			}
+		default:
+			return nil, fmt.Errorf("in expects a list, got %s", typeName(list))
+		}
+	}
+
+	for _, item := range items {
+		if equal(value, item) {
+			return !n.negate, nil
+		}
+	}
+	return n.negate, nil
+}
+
+// truthy reports whether a value counts as true
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != "" && v != "false"
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	if isNumber(value) {
+		f, _ := number(value)
+		return f != 0
+	}
+	return true
+}
+
+// equal compares two values. When one side is a number or boolean, a
+// string on the other side is converted to match.
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if isNumber(a) || isNumber(b) {
+		fa, okA := number(a)
+		fb, okB := number(b)
+		return okA && okB && fa == fb
+	}
+	if isBool(a) || isBool(b) {
+		ba, okA := boolean(a)
+		bb, okB := boolean(b)
+		return okA && okB && ba == bb
+	}
+	if sa, ok := a.(string); ok {
+		if sb, ok := b.(string); ok {
+			return sa == sb
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare orders two numbers or two strings
+func compare(a, b interface{}) (int, error) {
+	if isNumber(a) || isNumber(b) {
+		fa, okA := number(a)
+		fb, okB := number(b)
+		if okA && okB {
+			switch {
+			case fa < fb:
+				return -1, nil
+			case fa > fb:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	} else if sa, ok := a.(string); ok {
+		if sb, ok := b.(string); ok {
+			return strings.Compare(sa, sb), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+}
+
+// number converts numeric values and numeric strings to float64
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// boolean converts booleans and "true"/"false" strings
+func boolean(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+	return false, false
+}
+
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case float64, float32, int, int64:
+		return true
+	}
+	return false
+}
+
+func isBool(value interface{}) bool {
+	_, ok := value.(bool)
+	return ok
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}, []string:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	if isNumber(value) {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", value)
+}
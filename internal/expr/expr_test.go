@@ -0,0 +1,130 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	vars := map[string]interface{}{
+		"debug":   true,
+		"mode":    "development",
+		"message": "fix the build",
+		"limit":   float64(20),
+		"count":   "7",
+		"branch":  "release/1.2",
+		"tags":    []interface{}{"bug", "ui"},
+		"empty":   "",
+	}
+
+	tests := []struct {
+		condition string
+		expected  bool
+	}{
+		// The original ${var} op value form
+		{"${debug} == true", true},
+		{"${debug} == false", false},
+		{"${mode} != production", true},
+		{"${missing} == true", false},
+
+		// Typed comparisons
+		{"${limit} > 10", true},
+		{"${limit} <= 19.5", false},
+		{"${count} == 7", true},
+		{"${count} < 10", true},
+		{"${limit} == 20.0", true},
+		{`${mode} == "development"`, true},
+		{`${message} == 'fix the build'`, true},
+		{"${mode} > abc", true},
+		{"${missing} > 1", false},
+		{"${missing} == null", true},
+		{"${missing} != x", true},
+
+		// Logic
+		{"${debug} && ${limit} > 10", true},
+		{"${debug} and ${mode} == production", false},
+		{"${mode} == production || ${limit} >= 20", true},
+		{"not ${debug} or ${empty}", false},
+		{"!(${mode} == production && ${debug})", true},
+		{"${empty}", false},
+
+		// Membership
+		{`${mode} in [production, "development"]`, true},
+		{"${limit} in [10, 20]", true},
+		{"${mode} not in [production, staging]", true},
+		{"bug in ${tags}", true},
+		{"docs in ${tags}", false},
+		{"x in ${missing}", false},
+
+		// Presence
+		{"exists(mode)", true},
+		{"exists(${missing})", false},
+		{"not exists(missing) && exists(empty)", true},
+
+		// Regular expressions
+		{`${branch} =~ "^release/\\d+"`, true},
+		{`${branch} !~ ^main$`, true},
+		{"${missing} =~ .", false},
+		{"${limit} =~ ^2", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			e, err := Parse(tt.condition)
+			require.NoError(t, err)
+			result, err := e.Eval(vars)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	vars := map[string]interface{}{"limit": float64(3), "mode": "dev", "debug": true}
+
+	e, err := Parse("${mode} > 1")
+	require.NoError(t, err)
+	_, err = e.Eval(vars)
+	assert.EqualError(t, err, `condition "${mode} > 1": cannot compare string with number`)
+
+	e, err = Parse("x in ${debug}")
+	require.NoError(t, err)
+	_, err = e.Eval(vars)
+	assert.ErrorContains(t, err, "in expects a list, got boolean")
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		condition string
+		wantErr   string
+	}{
+		{"${debug", "unterminated ${ at column 1"},
+		{"${a b} == 1", "invalid variable ${a b} at column 1"},
+		{`${mode} == "dev`, "unterminated string at column 12"},
+		{"${mode} ==", "expected a value, found end of condition at column 11"},
+		{"${mode} == dev prod", `unexpected "prod" at column 16`},
+		{"(${debug}", `expected ")", found end of condition at column 10`},
+		{"${mode} in [a b]", `expected ",", found "b" at column 15`},
+		{"${mode} =~ (", `expected a pattern after =~, found "(" at column 12`},
+		{`${mode} =~ "a("`, "invalid pattern"},
+		{"exists(1 2)", `expected ")", found "2" at column 10`},
+		{"${mode} == and", "quote it to use it as a string"},
+		{"${a} = 1", "unexpected '=' at column 6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			_, err := Parse(tt.condition)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	e, err := Parse("exists(a) && (${b} == 1 || ${a} in ${c})")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, e.Variables())
+	assert.Equal(t, "exists(a) && (${b} == 1 || ${a} in ${c})", e.String())
+}
@@ -0,0 +1,557 @@
+// Package jq implements the subset of jq used to filter structured tool
+// output. Filters are parsed once, when the config is loaded.
+package jq
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Filter is a parsed jq-style filter. Supported syntax:
+//
+//	.                 identity
+//	.foo .foo.bar     object fields ("quoted" or ["bracketed"] keys too)
+//	.[0] .[-1]        array index
+//	.[2:5]            array slice
+//	.[] .foo[]        iterate over array elements or object values
+//	.foo?             suppress errors for missing or mistyped values
+//	a | b             pipe
+//	keys, length      builtins
+//	select(.a == 1)   keep values where a comparison (==, !=, <, <=, >, >=) holds
+type Filter struct {
+	text   string
+	stages []jqStage
+	stream bool
+}
+
+// Parse parses a filter. An empty filter is the identity.
+func Parse(filter string) (*Filter, error) {
+	filter = strings.TrimSpace(filter)
+	f := &Filter{text: filter}
+	if filter == "" || filter == "." {
+		return f, nil
+	}
+
+	stages, err := parseJQ(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq filter %q: %w", filter, err)
+	}
+	f.stages = stages
+	for _, stage := range stages {
+		switch s := stage.(type) {
+		case *jqSelect:
+			f.stream = true
+		case *jqPath:
+			for _, step := range s.steps {
+				if step.kind == "iterate" {
+					f.stream = true
+				}
+			}
+		}
+	}
+	return f, nil
+}
+
+// String returns the filter as written
+func (f *Filter) String() string {
+	return f.text
+}
+
+// Streams reports whether the filter iterates or selects, and so can yield
+// any number of values
+func (f *Filter) Streams() bool {
+	return f.stream
+}
+
+// Apply runs the filter on decoded data. The shape of the result depends
+// only on the filter: a filter that streams always returns an array, even
+// of one or no values, and any other filter returns its single value, or
+// nil when an optional step yields nothing.
+func (f *Filter) Apply(data interface{}) (interface{}, error) {
+	if len(f.stages) == 0 {
+		return data, nil
+	}
+
+	values := []interface{}{Normalize(data)}
+	for _, stage := range f.stages {
+		var next []interface{}
+		for _, value := range values {
+			out, err := stage.apply(value)
+			if err != nil {
+				return nil, fmt.Errorf("jq filter %q: %w", f.text, err)
+			}
+			next = append(next, out...)
+		}
+		values = next
+	}
+
+	if f.stream {
+		if values == nil {
+			values = []interface{}{}
+		}
+		return values, nil
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return values[0], nil
+}
+
+// jqStage is one pipe-separated stage of a filter
+type jqStage interface {
+	apply(value interface{}) ([]interface{}, error)
+}
+
+// jqPath is a sequence of field, index, slice and iterate steps
+type jqPath struct {
+	steps []jqStep
+}
+
+type jqStep struct {
+	kind     string // "field", "index", "slice", "iterate"
+	field    string
+	index    int
+	from, to *int
+	optional bool
+}
+
+// jqBuiltin is a builtin function such as keys or length
+type jqBuiltin struct {
+	name string
+}
+
+// jqSelect keeps values for which a comparison holds
+type jqSelect struct {
+	left  *jqPath
+	op    string
+	right interface{}
+}
+
+// parseJQ splits a filter into pipe stages and parses each one
+func parseJQ(filter string) ([]jqStage, error) {
+	var stages []jqStage
+	for _, part := range splitTopLevel(filter, '|') {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "keys" || part == "length":
+			stages = append(stages, &jqBuiltin{name: part})
+		case strings.HasPrefix(part, "select(") && strings.HasSuffix(part, ")"):
+			sel, err := parseJQSelect(part[len("select(") : len(part)-1])
+			if err != nil {
+				return nil, err
+			}
+			stages = append(stages, sel)
+		case strings.HasPrefix(part, "."):
+			path, err := parseJQPath(part)
+			if err != nil {
+				return nil, err
+			}
+			stages = append(stages, path)
+		default:
+			return nil, fmt.Errorf("unsupported expression %q", part)
+		}
+	}
+	return stages, nil
+}
+
+// parseJQPath parses a path expression such as .items[0].name
+func parseJQPath(expr string) (*jqPath, error) {
+	path := &jqPath{}
+	i := 0
+	for i < len(expr) {
+		switch expr[i] {
+		case '.':
+			i++
+			if i >= len(expr) || expr[i] == '[' {
+				continue
+			}
+			if expr[i] == '"' {
+				end := strings.IndexByte(expr[i+1:], '"')
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated string in %q", expr)
+				}
+				path.steps = append(path.steps, jqStep{kind: "field", field: expr[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+			start := i
+			for i < len(expr) && (isIdentChar(expr[i])) {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("expected field name at offset %d in %q", start, expr)
+			}
+			path.steps = append(path.steps, jqStep{kind: "field", field: expr[start:i]})
+		case '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ in %q", expr)
+			}
+			step, err := parseJQBracket(strings.TrimSpace(expr[i+1 : i+end]))
+			if err != nil {
+				return nil, err
+			}
+			path.steps = append(path.steps, step)
+			i += end + 1
+		case '?':
+			if len(path.steps) == 0 {
+				return nil, fmt.Errorf("unexpected ? in %q", expr)
+			}
+			path.steps[len(path.steps)-1].optional = true
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d in %q", expr[i], i, expr)
+		}
+	}
+	return path, nil
+}
+
+// parseJQBracket parses the contents of [...]
+func parseJQBracket(inner string) (jqStep, error) {
+	if inner == "" {
+		return jqStep{kind: "iterate"}, nil
+	}
+	if strings.HasPrefix(inner, `"`) && strings.HasSuffix(inner, `"`) && len(inner) >= 2 {
+		return jqStep{kind: "field", field: inner[1 : len(inner)-1]}, nil
+	}
+	if colon := strings.IndexByte(inner, ':'); colon >= 0 {
+		step := jqStep{kind: "slice"}
+		for j, bound := range []string{inner[:colon], inner[colon+1:]} {
+			bound = strings.TrimSpace(bound)
+			if bound == "" {
+				continue
+			}
+			n, err := strconv.Atoi(bound)
+			if err != nil {
+				return jqStep{}, fmt.Errorf("invalid slice bound %q", bound)
+			}
+			if j == 0 {
+				step.from = &n
+			} else {
+				step.to = &n
+			}
+		}
+		return step, nil
+	}
+	n, err := strconv.Atoi(inner)
+	if err != nil {
+		return jqStep{}, fmt.Errorf("invalid index %q", inner)
+	}
+	return jqStep{kind: "index", index: n}, nil
+}
+
+// parseJQSelect parses the condition inside select(...)
+func parseJQSelect(cond string) (*jqSelect, error) {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		idx := strings.Index(cond, op)
+		if idx < 0 {
+			continue
+		}
+		left, err := parseJQPath(strings.TrimSpace(cond[:idx]))
+		if err != nil {
+			return nil, err
+		}
+		var right interface{}
+		literal := strings.TrimSpace(cond[idx+len(op):])
+		if err := json.Unmarshal([]byte(literal), &right); err != nil {
+			return nil, fmt.Errorf("invalid literal %q in select", literal)
+		}
+		return &jqSelect{left: left, op: op, right: right}, nil
+	}
+
+	// select(.flag) keeps truthy values
+	left, err := parseJQPath(strings.TrimSpace(cond))
+	if err != nil {
+		return nil, err
+	}
+	return &jqSelect{left: left}, nil
+}
+
+func (p *jqPath) apply(value interface{}) ([]interface{}, error) {
+	values := []interface{}{value}
+	for _, step := range p.steps {
+		var next []interface{}
+		for _, v := range values {
+			out, err := step.apply(v)
+			if err != nil {
+				if step.optional {
+					continue
+				}
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+func (s jqStep) apply(value interface{}) ([]interface{}, error) {
+	switch s.kind {
+	case "field":
+		if value == nil {
+			return []interface{}{nil}, nil
+		}
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s with %q", jsonTypeName(value), s.field)
+		}
+		return []interface{}{obj[s.field]}, nil
+
+	case "index":
+		if value == nil {
+			return []interface{}{nil}, nil
+		}
+		arr, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s with number", jsonTypeName(value))
+		}
+		idx := s.index
+		if idx < 0 {
+			idx += len(arr)
+		}
+		if idx < 0 || idx >= len(arr) {
+			return []interface{}{nil}, nil
+		}
+		return []interface{}{arr[idx]}, nil
+
+	case "slice":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot slice %s", jsonTypeName(value))
+		}
+		from, to := 0, len(arr)
+		if s.from != nil {
+			from = clampIndex(*s.from, len(arr))
+		}
+		if s.to != nil {
+			to = clampIndex(*s.to, len(arr))
+		}
+		if from > to {
+			from = to
+		}
+		return []interface{}{append([]interface{}{}, arr[from:to]...)}, nil
+
+	case "iterate":
+		switch v := value.(type) {
+		case []interface{}:
+			return v, nil
+		case map[string]interface{}:
+			result := make([]interface{}, 0, len(v))
+			for _, key := range sortedMapKeys(v) {
+				result = append(result, v[key])
+			}
+			return result, nil
+		default:
+			return nil, fmt.Errorf("cannot iterate over %s", jsonTypeName(value))
+		}
+	}
+	return nil, fmt.Errorf("unknown step %s", s.kind)
+}
+
+func (b *jqBuiltin) apply(value interface{}) ([]interface{}, error) {
+	switch b.name {
+	case "keys":
+		switch v := value.(type) {
+		case map[string]interface{}:
+			keys := make([]interface{}, 0, len(v))
+			for _, key := range sortedMapKeys(v) {
+				keys = append(keys, key)
+			}
+			return []interface{}{keys}, nil
+		case []interface{}:
+			keys := make([]interface{}, len(v))
+			for i := range v {
+				keys[i] = float64(i)
+			}
+			return []interface{}{keys}, nil
+		}
+		return nil, fmt.Errorf("%s has no keys", jsonTypeName(value))
+
+	case "length":
+		switch v := value.(type) {
+		case nil:
+			return []interface{}{float64(0)}, nil
+		case string:
+			return []interface{}{float64(len([]rune(v)))}, nil
+		case []interface{}:
+			return []interface{}{float64(len(v))}, nil
+		case map[string]interface{}:
+			return []interface{}{float64(len(v))}, nil
+		}
+		return nil, fmt.Errorf("%s has no length", jsonTypeName(value))
+	}
+	return nil, fmt.Errorf("unknown builtin %s", b.name)
+}
+
+func (s *jqSelect) apply(value interface{}) ([]interface{}, error) {
+	lefts, err := s.left.apply(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, left := range lefts {
+		if s.op == "" {
+			if left != nil && left != false {
+				return []interface{}{value}, nil
+			}
+			continue
+		}
+		if compareJSON(left, s.op, s.right) {
+			return []interface{}{value}, nil
+		}
+	}
+	return nil, nil
+}
+
+// compareJSON compares two decoded JSON values
+func compareJSON(left interface{}, op string, right interface{}) bool {
+	switch op {
+	case "==":
+		return fmt.Sprintf("%#v", left) == fmt.Sprintf("%#v", right)
+	case "!=":
+		return fmt.Sprintf("%#v", left) != fmt.Sprintf("%#v", right)
+	}
+
+	if l, ok := left.(float64); ok {
+		if r, ok := right.(float64); ok {
+			switch op {
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			}
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch op {
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			}
+		}
+	}
+	return false
+}
+
+// Normalize converts decoded data to the types encoding/json produces,
+// so filters behave the same whatever format the data came from
+func Normalize(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[key] = Normalize(value)
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[fmt.Sprintf("%v", key)] = Normalize(value)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = Normalize(value)
+		}
+		return result
+	case []map[string]interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = Normalize(value)
+		}
+		return result
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+// splitTopLevel splits s on sep, ignoring separators inside quotes,
+// brackets and parentheses
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth := 0
+	inString := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' && (i == 0 || s[i-1] != '\\'):
+			inString = !inString
+		case inString:
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
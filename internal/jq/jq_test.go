@@ -0,0 +1,117 @@
+package jq
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	input := `{
+		"name": "umcp",
+		"items": [
+			{"id": 1, "tags": ["a", "b"], "state": "open"},
+			{"id": 2, "tags": [], "state": "closed"},
+			{"id": 3, "tags": ["c"], "state": "open"}
+		],
+		"meta": {"dotted key": true}
+	}`
+
+	tests := []struct {
+		name     string
+		filter   string
+		expected string
+	}{
+		{name: "identity", filter: ".", expected: input},
+		{name: "field", filter: ".name", expected: `"umcp"`},
+		{name: "nested index", filter: ".items[0].id", expected: `1`},
+		{name: "negative index", filter: ".items[-1].id", expected: `3`},
+		{name: "iterate", filter: ".items[].id", expected: `[1, 2, 3]`},
+		{name: "pipe", filter: ".items | .[1] | .state", expected: `"closed"`},
+		{name: "slice", filter: ".items[1:] | length", expected: `2`},
+		{name: "quoted key", filter: `.meta."dotted key"`, expected: `true`},
+		{name: "bracket key", filter: `.meta["dotted key"]`, expected: `true`},
+		{name: "missing field", filter: ".missing.deeper", expected: `null`},
+		{name: "keys", filter: ".items[0] | keys", expected: `["id", "state", "tags"]`},
+		{name: "select", filter: `.items[] | select(.state == "open") | .id`, expected: `[1, 3]`},
+		{name: "select numeric", filter: `.items[] | select(.id >= 2) | .id`, expected: `[2, 3]`},
+		{name: "optional", filter: ".items[].tags[0]?", expected: `["a", null, "c"]`},
+		{name: "no results", filter: `.items[] | select(.id > 10)`, expected: `[]`},
+		{name: "one result", filter: `.items[] | select(.id == 2) | .id`, expected: `[2]`},
+		{name: "iterate one element", filter: ".items[2].tags[]", expected: `["c"]`},
+		{name: "optional missing", filter: ".name[0]?", expected: `null`},
+	}
+
+	var data interface{}
+	require.NoError(t, json.Unmarshal([]byte(input), &data))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := Parse(tt.filter)
+			require.NoError(t, err)
+			result, err := filter.Apply(data)
+			require.NoError(t, err)
+			encoded, err := json.Marshal(result)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(encoded))
+		})
+	}
+}
+
+func TestStreams(t *testing.T) {
+	for filter, expected := range map[string]bool{
+		"":                       false,
+		".items[0].id":           false,
+		".name?":                 false,
+		".items | length":        false,
+		".items[].id":            true,
+		".items | .[]":           true,
+		`select(.id == 1) | .id`: true,
+	} {
+		f, err := Parse(filter)
+		require.NoError(t, err, filter)
+		assert.Equal(t, expected, f.Streams(), filter)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, filter := range []string{"map(.id)", ".items[x]", ".items[0"} {
+		_, err := Parse(filter)
+		assert.ErrorContains(t, err, "invalid jq filter", filter)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{name: "index string", filter: ".name[0]"},
+		{name: "iterate number", filter: ".count[]"},
+	}
+
+	data := map[string]interface{}{"name": "x", "count": float64(1), "items": []interface{}{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := Parse(tt.filter)
+			require.NoError(t, err)
+			_, err = filter.Apply(data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApplyNormalizesTypes(t *testing.T) {
+	data := map[interface{}]interface{}{"n": 1, "list": []interface{}{int64(2)}}
+	filter, err := Parse(".list[0]")
+	require.NoError(t, err)
+	result, err := filter.Apply(data)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), result)
+
+	encoded, err := json.Marshal(Normalize(data))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"n": 1, "list": [2]}`, string(encoded))
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CancelledParams are the parameters of a notifications/cancelled message
+type CancelledParams struct {
+	RequestID interface{} `json:"requestId"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+// callTracker gives each tool call a context with its deadline and keeps
+// the running calls, so a client can cancel them while they run
+type callTracker struct {
+	mu      sync.Mutex
+	base    context.Context
+	running map[string]context.CancelFunc
+}
+
+func newCallTracker() *callTracker {
+	return &callTracker{
+		base:    context.Background(),
+		running: make(map[string]context.CancelFunc),
+	}
+}
+
+// setBase makes calls started from now on end when ctx does
+func (t *callTracker) setBase(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.base = ctx
+}
+
+// start returns the context for the call with the given request ID. A
+// timeout of zero means no deadline. done must be called when the call
+// finishes.
+func (t *callTracker) start(id interface{}, timeout time.Duration) (context.Context, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(t.base, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(t.base)
+	}
+	key := fmt.Sprint(id)
+	t.running[key] = cancel
+	return ctx, func() {
+		t.mu.Lock()
+		delete(t.running, key)
+		t.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancel cancels the call a notifications/cancelled message names. Calls
+// that already finished are ignored, as the protocol allows.
+func (t *callTracker) cancel(raw json.RawMessage) {
+	var params CancelledParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		log.Warn().Err(err).Msg("Invalid cancellation")
+		return
+	}
+
+	t.mu.Lock()
+	cancel, ok := t.running[fmt.Sprint(params.RequestID)]
+	t.mu.Unlock()
+	if ok {
+		log.Info().
+			Interface("id", params.RequestID).
+			Str("reason", params.Reason).
+			Msg("Canceling tool call")
+		cancel()
+	}
+}
@@ -0,0 +1,38 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallTracker(t *testing.T) {
+	calls := newCallTracker()
+
+	ctx, done := calls.start(float64(7), time.Minute)
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// Cancellations for other or finished calls are ignored
+	calls.cancel([]byte(`{"requestId": 8}`))
+	calls.cancel([]byte(`not json`))
+	assert.NoError(t, ctx.Err())
+
+	calls.cancel([]byte(`{"requestId": 7, "reason": "user aborted"}`))
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	done()
+	assert.Empty(t, calls.running)
+
+	// Stopping the server ends calls started from its context
+	base, stop := context.WithCancel(context.Background())
+	calls.setBase(base)
+	ctx, done = calls.start("req-1", 0)
+	defer done()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+	stop()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
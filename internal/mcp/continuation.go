@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/charignon/umcp/internal/parser"
+)
+
+// ReadMoreTool is the name of the built-in tool that pages through
+// truncated output
+const ReadMoreTool = "umcp_read_more"
+
+// maxContinuations bounds how many truncated outputs are kept in memory
+const maxContinuations = 32
+
+// continuation is the unread remainder of a truncated tool result
+type continuation struct {
+	remaining string
+	pageSize  int
+}
+
+// continuationStore keeps truncated tool output so the model can page
+// through it. The oldest entries are evicted first.
+type continuationStore struct {
+	mu      sync.Mutex
+	entries map[string]*continuation
+	order   []string
+}
+
+// newContinuationStore creates an empty store
+func newContinuationStore() *continuationStore {
+	return &continuationStore{entries: make(map[string]*continuation)}
+}
+
+// Save stores a remainder and returns its handle
+func (c *continuationStore) Save(remaining string, pageSize int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	handle := hex.EncodeToString(b)
+
+	c.entries[handle] = &continuation{remaining: remaining, pageSize: pageSize}
+	c.order = append(c.order, handle)
+	for len(c.order) > maxContinuations {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	return handle
+}
+
+// Next returns the next page for a handle and how many bytes remain after
+// it. The handle is released once everything has been read.
+func (c *continuationStore) Next(handle string) (string, int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[handle]
+	if !ok {
+		return "", 0, fmt.Errorf("unknown or expired continuation handle: %s", handle)
+	}
+
+	page, rest := parser.Truncate(entry.remaining, entry.pageSize)
+	entry.remaining = rest
+	if rest == "" {
+		delete(c.entries, handle)
+		for i, h := range c.order {
+			if h == handle {
+				c.order = append(c.order[:i], c.order[i+1:]...)
+				break
+			}
+		}
+	}
+	return page, len(rest), nil
+}
+
+// readMoreToolInfo describes the built-in read more tool
+func readMoreToolInfo() ToolInfo {
+	return ToolInfo{
+		Name:        ReadMoreTool,
+		Description: "Read the next page of a truncated tool result, using the handle from the truncation notice",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"handle": {
+					Type:        "string",
+					Description: "Continuation handle from the truncation notice",
+				},
+			},
+			Required: []string{"handle"},
+		},
+	}
+}
+
+// truncationNotice tells the model how to read the rest of the output
+func truncationNotice(handle string, remaining int) string {
+	return fmt.Sprintf("[Output truncated: %d more bytes. Call %s with handle %q to read more.]",
+		remaining, ReadMoreTool, handle)
+}
@@ -0,0 +1,40 @@
+package mcp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContinuationStorePaging(t *testing.T) {
+	store := newContinuationStore()
+	handle := store.Save("line 1\nline 2\nline 3\n", 8)
+
+	var pages []string
+	for {
+		page, remaining, err := store.Next(handle)
+		require.NoError(t, err)
+		pages = append(pages, page)
+		if remaining == 0 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"line 1\n", "line 2\n", "line 3\n"}, pages)
+
+	_, _, err := store.Next(handle)
+	assert.ErrorContains(t, err, "unknown or expired continuation handle")
+}
+
+func TestContinuationStoreEviction(t *testing.T) {
+	store := newContinuationStore()
+	first := store.Save("old", 10)
+	for i := 0; i < maxContinuations; i++ {
+		store.Save(fmt.Sprintf("entry %d", i), 10)
+	}
+
+	_, _, err := store.Next(first)
+	assert.Error(t, err)
+	assert.Len(t, store.entries, maxContinuations)
+}
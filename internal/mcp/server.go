@@ -1,22 +1,38 @@
 package mcp
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"syscall"
 
+	"github.com/charignon/umcp/internal/audit"
 	"github.com/charignon/umcp/internal/config"
 	"github.com/charignon/umcp/internal/debug"
 	"github.com/charignon/umcp/internal/executor"
+	"github.com/charignon/umcp/internal/metrics"
+	"github.com/charignon/umcp/internal/telemetry"
 	"github.com/rs/zerolog/log"
 )
 
 // ServerOptions contains options for server configuration
 type ServerOptions struct {
-	DebugMode   bool
-	DebugTrace  string
-	ReplayTrace string
+	DebugMode     bool
+	DebugTrace    string
+	ReplayTrace   string
+	SpanFile      string // Export request spans as JSON lines to this file
+	OTLPEndpoint  string // Export request spans to this OTLP/HTTP collector
+	MetricsListen string // Serve Prometheus metrics on this address
+	AuditLog      string // Append a hash-chained record of every tool call to this file
 }
 
 // Server represents an MCP server instance
@@ -26,6 +42,13 @@ type Server struct {
 	executor *executor.CommandExecutor
 	tools    map[string]*config.Tool
 	tracer   *debug.Tracer
+	spans    *telemetry.Tracer
+	metrics  *metrics.Metrics
+	audit    *audit.Logger
+	session  string
+	client   ClientInfo
+	pages    *continuationStore
+	calls    *callTracker
 }
 
 // NewServer creates a new MCP server
@@ -48,15 +71,74 @@ func NewServer(configs []*config.Config, opts ServerOptions) *Server {
 		tracer, _ = debug.NewTracer(false, "")
 	}
 
+	// Setup span exporters
+	var exporters []telemetry.Exporter
+	if opts.SpanFile != "" {
+		exporter, err := telemetry.NewFileExporter(opts.SpanFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to setup span file exporter")
+		}
+		exporters = append(exporters, exporter)
+	}
+	if opts.OTLPEndpoint != "" {
+		exporter, err := telemetry.NewOTLPExporter(opts.OTLPEndpoint, "umcp")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to setup OTLP exporter")
+		}
+		exporters = append(exporters, exporter)
+	}
+
 	exec := executor.NewCommandExecutor()
 	exec.SetTracer(tracer)
 
+	var spans *telemetry.Tracer
+	if len(exporters) > 0 {
+		spans = telemetry.NewTracer(exporters...)
+		exec.AddTracer(spans)
+	}
+
+	var m *metrics.Metrics
+	if opts.MetricsListen != "" {
+		m = metrics.New()
+		exec.AddTracer(m)
+
+		listener, err := net.Listen("tcp", opts.MetricsListen)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to listen for metrics")
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m.Handler())
+		go func() {
+			if err := http.Serve(listener, mux); err != nil {
+				log.Error().Err(err).Msg("Metrics server failed")
+			}
+		}()
+		log.Info().Str("address", listener.Addr().String()).Msg("Serving metrics")
+	}
+
+	session := newSessionID()
+
+	var auditLog *audit.Logger
+	if opts.AuditLog != "" {
+		auditLog, err = audit.NewLogger(opts.AuditLog, session)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to open audit log")
+		}
+		exec.AddTracer(auditLog)
+	}
+
 	server := &Server{
 		configs:  configs,
 		protocol: NewProtocol(os.Stdin, os.Stdout),
 		executor: exec,
 		tools:    make(map[string]*config.Tool),
 		tracer:   tracer,
+		spans:    spans,
+		metrics:  m,
+		audit:    auditLog,
+		session:  session,
+		pages:    newContinuationStore(),
+		calls:    newCallTracker(),
 	}
 
 	// Index all tools
@@ -81,15 +163,38 @@ func (s *Server) Run() error {
 			s.tracer.PrintSummary()
 			s.tracer.Close()
 		}
+		if err := s.spans.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close span exporters")
+		}
+		if err := s.audit.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close audit log")
+		}
 	}()
 
+	// Stopping the server cancels the running call
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s.calls.setBase(ctx)
+
+	requests := make(chan incoming, 64)
+	go s.readRequests(requests)
+
 	for {
-		req, err := s.protocol.ReadRequest()
-		if err != nil {
-			if err == io.EOF {
+		var next incoming
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Server stopped")
+			return nil
+		case received, ok := <-requests:
+			if !ok {
 				log.Info().Msg("Client disconnected")
 				return nil
 			}
+			next = received
+		}
+
+		req, err := next.req, next.err
+		if err != nil {
 			log.Error().Err(err).Msg("Failed to read request")
 			continue
 		}
@@ -100,7 +205,16 @@ func (s *Server) Run() error {
 			"id":     req.ID,
 		})
 
-		if err := s.handleRequest(req); err != nil {
+		span := s.spans.StartSpan(req.Method, map[string]interface{}{
+			"rpc.system":     "jsonrpc",
+			"rpc.method":     req.Method,
+			"rpc.request_id": fmt.Sprintf("%v", req.ID),
+		})
+		err = s.handleRequest(req)
+		span.RecordError(err)
+		span.End()
+
+		if err != nil {
 			log.Error().Err(err).Msg("Failed to handle request")
 
 			// Trace error response
@@ -117,6 +231,30 @@ func (s *Server) Run() error {
 	}
 }
 
+// incoming is a request read from the client, or the error reading it
+type incoming struct {
+	req *Request
+	err error
+}
+
+// readRequests reads requests until the client disconnects, then closes
+// requests. Cancellations are handled as they arrive, so they reach a call
+// that is still running; other requests are queued for Run.
+func (s *Server) readRequests(requests chan<- incoming) {
+	defer close(requests)
+	for {
+		req, err := s.protocol.ReadRequest()
+		if err == io.EOF {
+			return
+		}
+		if err == nil && req.Method == "notifications/cancelled" {
+			s.calls.cancel(req.Params)
+			continue
+		}
+		requests <- incoming{req: req, err: err}
+	}
+}
+
 // handleRequest processes a JSON-RPC request
 func (s *Server) handleRequest(req *Request) error {
 	switch req.Method {
@@ -138,6 +276,21 @@ func (s *Server) handleRequest(req *Request) error {
 	}
 }
 
+// protocolVersions are the MCP versions the server speaks, newest first.
+// Output schemas and structured content need 2025-06-18.
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// negotiateVersion returns the version the client asked for if the server
+// speaks it, and the newest version otherwise, as the protocol prescribes
+func negotiateVersion(requested string) string {
+	for _, version := range protocolVersions {
+		if version == requested {
+			return version
+		}
+	}
+	return protocolVersions[0]
+}
+
 // handleInitialize handles the initialize request
 func (s *Server) handleInitialize(req *Request) error {
 	var params InitializeParams
@@ -146,9 +299,10 @@ func (s *Server) handleInitialize(req *Request) error {
 			return s.protocol.SendError(req.ID, InvalidParams, "Invalid parameters", err.Error())
 		}
 	}
+	s.client = params.ClientInfo
 
 	result := InitializeResult{
-		ProtocolVersion: "2024-11-05",
+		ProtocolVersion: negotiateVersion(params.ProtocolVersion),
 		Capabilities: ServerCapabilities{
 			Tools: ToolsCapability{
 				ListChanged: false,
@@ -182,17 +336,16 @@ func (s *Server) handleToolsList(req *Request) error {
 			required := []string{}
 
 			for _, arg := range tool.Arguments {
-				prop := Property{
-					Type:        s.mapArgTypeToJSONSchema(arg.Type),
+				prop := s.valueProperty(&config.Value{
+					Type:        arg.Type,
 					Description: arg.Description,
-					Default:     arg.Default,
-					Minimum:     arg.Min,
-					Maximum:     arg.Max,
-				}
-
-				if arg.Type == "array" {
-					prop.Items = &Property{Type: "string"}
-				}
+					Format:      arg.Format,
+					Min:         arg.Min,
+					Max:         arg.Max,
+					Items:       arg.Items,
+					Properties:  arg.Properties,
+				})
+				prop.Default = arg.Default
 
 				properties[arg.Name] = prop
 
@@ -201,18 +354,35 @@ func (s *Server) handleToolsList(req *Request) error {
 				}
 			}
 
-			tools = append(tools, ToolInfo{
+			// Calls that confirm_when applies to must set confirm
+			if tool.ConfirmWhen != "" && tool.Argument("confirm") == nil {
+				properties["confirm"] = Property{
+					Type:        "boolean",
+					Description: fmt.Sprintf("Confirm the call; needed when %s", tool.ConfirmWhen),
+				}
+			}
+
+			inputSchema := InputSchema{
+				Type:       "object",
+				Properties: properties,
+				Required:   required,
+			}
+			addConstraints(&inputSchema, tool.Constraints)
+
+			info := ToolInfo{
 				Name:        fullName,
 				Description: tool.Description,
-				InputSchema: InputSchema{
-					Type:       "object",
-					Properties: properties,
-					Required:   required,
-				},
-			})
+				InputSchema: inputSchema,
+			}
+			if tool.Output.PublishesSchema() {
+				info.OutputSchema = tool.Output.Schema
+			}
+			tools = append(tools, info)
 		}
 	}
 
+	tools = append(tools, readMoreToolInfo())
+
 	result := ToolsListResult{Tools: tools}
 
 	// Trace outgoing response
@@ -232,6 +402,10 @@ func (s *Server) handleToolCall(req *Request) error {
 		return s.protocol.SendError(req.ID, InvalidParams, "Invalid parameters", err.Error())
 	}
 
+	if params.Name == ReadMoreTool {
+		return s.handleReadMore(req, params)
+	}
+
 	tool, exists := s.tools[params.Name]
 	if !exists {
 		return s.protocol.SendError(req.ID, InvalidParams,
@@ -262,10 +436,32 @@ func (s *Server) handleToolCall(req *Request) error {
 		"config":    toolConfig.Metadata.Name,
 	})
 
+	span := s.spans.Current()
+	span.SetAttribute("umcp.tool", params.Name)
+	span.SetAttribute("umcp.config", toolConfig.Metadata.Name)
+
+	var sensitive []string
+	for _, arg := range tool.Arguments {
+		if arg.Sensitive {
+			sensitive = append(sensitive, arg.Name)
+		}
+	}
+	s.audit.StartCall(audit.Client{Name: s.client.Name, Version: s.client.Version},
+		params.Name, params.Arguments, sensitive)
+
 	// Execute the command
-	output, err := s.executor.Execute(toolConfig, tool, params.Arguments)
+	s.metrics.CallStarted(params.Name)
+	ctx, done := s.calls.start(req.ID, toolConfig.Settings.CallTimeout)
+	output, err := s.executor.ExecuteContext(ctx, toolConfig, tool, params.Arguments)
+	done()
+	s.metrics.CallFinished(params.Name, err)
+	if auditErr := s.audit.FinishCall(err); auditErr != nil {
+		log.Error().Err(auditErr).Msg("Failed to write audit record")
+	}
 
 	if err != nil {
+		span.RecordError(err)
+
 		result := ToolCallResult{
 			Content: []ContentItem{{
 				Type: "text",
@@ -273,6 +469,28 @@ func (s *Server) handleToolCall(req *Request) error {
 			}},
 			IsError: true,
 		}
+		if output != nil {
+			span.SetAttribute("umcp.output_bytes", len(output.Stdout))
+			if output.Error != nil && output.Error.Hint != "" {
+				result.Content = append(result.Content, ContentItem{
+					Type: "text",
+					Text: "Hint: " + output.Error.Hint,
+				})
+			}
+			if output.Stdout != "" {
+				result.Content = append(result.Content, ContentItem{
+					Type: "text",
+					Text: "stdout:\n" + output.Stdout,
+				})
+			}
+			if output.Stderr != "" {
+				result.Content = append(result.Content, ContentItem{
+					Type: "text",
+					Text: "stderr:\n" + output.Stderr,
+				})
+			}
+			result.Meta = resultMeta(output)
+		}
 
 		// Trace error result
 		s.tracer.TraceOutgoing("tool_error", result, map[string]interface{}{
@@ -284,12 +502,48 @@ func (s *Server) handleToolCall(req *Request) error {
 
 		return s.protocol.SendResult(req.ID, result)
 	}
+	span.SetAttribute("umcp.output_bytes", len(output.Stdout))
 
 	result := ToolCallResult{
 		Content: []ContentItem{{
 			Type: "text",
-			Text: output,
+			Text: output.Output,
 		}},
+		Meta: resultMeta(output),
+	}
+	if output.Binary != nil {
+		result.Content = []ContentItem{binaryContent(tool.Output.Type, output.Binary)}
+	}
+	if output.ExitCode != 0 {
+		result.Content = append(result.Content, ContentItem{
+			Type: "text",
+			Text: fmt.Sprintf("exit code: %d", output.ExitCode),
+		})
+	}
+	if output.Stderr != "" {
+		result.Content = append(result.Content, ContentItem{
+			Type: "text",
+			Text: "stderr:\n" + output.Stderr,
+		})
+	}
+	for _, warning := range output.Warnings {
+		result.Content = append(result.Content, ContentItem{
+			Type: "text",
+			Text: "Warning: " + warning,
+		})
+	}
+	// Structured content is an object and is sent whole, even when the
+	// text is paged, so results always match a published outputSchema
+	if structured, ok := output.Structured.(map[string]interface{}); ok {
+		result.StructuredContent = structured
+	}
+	if output.Remainder != "" {
+		handle := s.pages.Save(output.Remainder, output.PageSize)
+		result.Content = append(result.Content, ContentItem{
+			Type: "text",
+			Text: truncationNotice(handle, len(output.Remainder)),
+		})
+		result.Meta["continuation"] = handle
 	}
 
 	// Trace successful result
@@ -297,12 +551,78 @@ func (s *Server) handleToolCall(req *Request) error {
 		"method":      "tools/call",
 		"id":          req.ID,
 		"tool_name":   params.Name,
-		"output_size": len(output),
+		"output_size": len(output.Output),
+	})
+
+	return s.protocol.SendResult(req.ID, result)
+}
+
+// handleReadMore returns the next page of a truncated tool result
+func (s *Server) handleReadMore(req *Request, params ToolCallParams) error {
+	handle, _ := params.Arguments["handle"].(string)
+	page, remaining, err := s.pages.Next(handle)
+
+	var result ToolCallResult
+	if err != nil {
+		result = ToolCallResult{
+			Content: []ContentItem{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}
+	} else {
+		result = ToolCallResult{
+			Content: []ContentItem{{Type: "text", Text: page}},
+		}
+		if remaining > 0 {
+			result.Content = append(result.Content, ContentItem{
+				Type: "text",
+				Text: truncationNotice(handle, remaining),
+			})
+			result.Meta = map[string]interface{}{"continuation": handle}
+		}
+	}
+
+	s.tracer.TraceOutgoing("tool_result", result, map[string]interface{}{
+		"method":    "tools/call",
+		"id":        req.ID,
+		"tool_name": ReadMoreTool,
+		"remaining": remaining,
 	})
 
 	return s.protocol.SendResult(req.ID, result)
 }
 
+// binaryContent returns image output as image content and other binary
+// output as an embedded resource
+func binaryContent(outputType string, binary *executor.Binary) ContentItem {
+	data := base64.StdEncoding.EncodeToString(binary.Data)
+	if outputType == "image" {
+		return ContentItem{Type: "image", Data: data, MimeType: binary.MimeType}
+	}
+	return ContentItem{
+		Type: "resource",
+		Resource: &ResourceContents{
+			URI:      binary.URI,
+			MimeType: binary.MimeType,
+			Blob:     data,
+		},
+	}
+}
+
+// resultMeta builds the _meta block describing a command execution
+func resultMeta(result *executor.Result) map[string]interface{} {
+	meta := map[string]interface{}{
+		"exitCode":   result.ExitCode,
+		"durationMs": result.Duration.Milliseconds(),
+	}
+	if result.Error != nil {
+		meta["error"] = result.Error
+	}
+	if result.Attempts > 1 {
+		meta["attempts"] = result.Attempts
+	}
+	return meta
+}
+
 // handlePromptsList handles the prompts/list request
 func (s *Server) handlePromptsList(req *Request) error {
 	// UMCP currently doesn't support prompts, so return empty list
@@ -348,6 +668,41 @@ func (s *Server) handleNotificationInitialized(req *Request) error {
 	return nil
 }
 
+// valueProperty describes an argument, array item or object property in
+// JSON Schema. Arrays without declared items hold strings.
+func (s *Server) valueProperty(value *config.Value) Property {
+	prop := Property{
+		Type:        s.mapArgTypeToJSONSchema(value.Type),
+		Description: value.Description,
+		Format:      value.Format,
+		Minimum:     value.Min,
+		Maximum:     value.Max,
+	}
+	if value.Type == "path" && prop.Format == "" {
+		prop.Format = "path"
+	}
+
+	if value.Type == "array" {
+		items := Property{Type: "string"}
+		if value.Items != nil {
+			items = s.valueProperty(value.Items)
+		}
+		prop.Items = &items
+	}
+
+	if len(value.Properties) > 0 {
+		prop.Properties = make(map[string]Property, len(value.Properties))
+		for name, property := range value.Properties {
+			prop.Properties[name] = s.valueProperty(property)
+			if property.Required {
+				prop.Required = append(prop.Required, name)
+			}
+		}
+		sort.Strings(prop.Required)
+	}
+	return prop
+}
+
 // mapArgTypeToJSONSchema maps argument types to JSON Schema types
 func (s *Server) mapArgTypeToJSONSchema(argType string) string {
 	switch argType {
@@ -364,4 +719,90 @@ func (s *Server) mapArgTypeToJSONSchema(argType string) string {
 	default:
 		return "string"
 	}
+}
+
+// addConstraints expresses argument constraints in an input schema.
+// one_of becomes oneOf, at_least_one and requires anyOf and exclusive not,
+// combined with allOf when a tool has several.
+// Arguments count as given the way the executor counts them, so false, ""
+// and [] do not.
+func addConstraints(schema *InputSchema, constraints []config.Constraint) {
+	var rules []map[string]interface{}
+	for _, constraint := range constraints {
+		switch {
+		case len(constraint.OneOf) > 0:
+			rules = append(rules, map[string]interface{}{"oneOf": givenEach(constraint.OneOf)})
+		case len(constraint.AtLeastOne) > 0:
+			rules = append(rules, map[string]interface{}{"anyOf": givenEach(constraint.AtLeastOne)})
+		case len(constraint.Exclusive) > 0:
+			// At most one: no two of the arguments are given together
+			var pairs []map[string]interface{}
+			for i, a := range constraint.Exclusive {
+				for _, b := range constraint.Exclusive[i+1:] {
+					pairs = append(pairs, givenAll(a, b))
+				}
+			}
+			not := pairs[0]
+			if len(pairs) > 1 {
+				not = map[string]interface{}{"anyOf": pairs}
+			}
+			rules = append(rules, map[string]interface{}{"not": not})
+		default:
+			names := make([]string, 0, len(constraint.Requires))
+			for name := range constraint.Requires {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				// Either name is not given or everything it requires is
+				rules = append(rules, map[string]interface{}{"anyOf": []map[string]interface{}{
+					{"not": givenAll(name)},
+					givenAll(constraint.Requires[name]...),
+				}})
+			}
+		}
+	}
+
+	if len(rules) != 1 {
+		schema.AllOf = rules
+		return
+	}
+	for key, value := range rules[0] {
+		switch key {
+		case "oneOf":
+			schema.OneOf = value.([]map[string]interface{})
+		case "anyOf":
+			schema.AnyOf = value.([]map[string]interface{})
+		case "not":
+			schema.Not = value.(map[string]interface{})
+		}
+	}
+}
+
+// givenAll returns a schema matching calls that give every named argument:
+// each is present and not null, false, "" or []
+func givenAll(names ...string) map[string]interface{} {
+	properties := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		properties[name] = map[string]interface{}{
+			"not": map[string]interface{}{"enum": []interface{}{nil, false, "", []interface{}{}}},
+		}
+	}
+	return map[string]interface{}{"required": names, "properties": properties}
+}
+
+// givenEach returns one givenAll schema per name
+func givenEach(names []string) []map[string]interface{} {
+	schemas := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		schemas[i] = givenAll(name)
+	}
+	return schemas
+}
+
+// newSessionID returns a random identifier for this server session
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
\ No newline at end of file
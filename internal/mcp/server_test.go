@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/charignon/umcp/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddConstraints(t *testing.T) {
+	inputSchema := InputSchema{Type: "object"}
+	addConstraints(&inputSchema, []config.Constraint{{Exclusive: []string{"quiet", "format"}}})
+
+	data, err := json.Marshal(inputSchema)
+	require.NoError(t, err)
+	notGiven := `{"not": {"enum": [null, false, "", []]}}`
+	assert.JSONEq(t, `{
+		"type": "object",
+		"not": {
+			"required": ["quiet", "format"],
+			"properties": {"quiet": `+notGiven+`, "format": `+notGiven+`}
+		}
+	}`, string(data))
+
+	inputSchema = InputSchema{Type: "object"}
+	addConstraints(&inputSchema, []config.Constraint{
+		{Exclusive: []string{"a", "b", "c"}},
+		{AtLeastOne: []string{"paths", "all"}},
+	})
+	data, err = json.Marshal(inputSchema)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Len(t, decoded["allOf"], 2)
+}
+
+// TestAddConstraintsMatchesExecutor checks the published schema accepts
+// and rejects the same calls as the executor's constraint check
+func TestAddConstraintsMatchesExecutor(t *testing.T) {
+	inputSchema := InputSchema{Type: "object"}
+	addConstraints(&inputSchema, []config.Constraint{
+		{Exclusive: []string{"quiet", "format"}},
+		{Requires: map[string][]string{"ports": {"image"}}},
+		{OneOf: []string{"image", "dockerfile"}},
+		{AtLeastOne: []string{"paths", "all"}},
+	})
+	data, err := json.Marshal(inputSchema)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	tests := []struct {
+		args  string
+		valid bool
+	}{
+		{args: `{"image": "alpine", "all": true}`, valid: true},
+		{args: `{"quiet": false, "format": "{{.ID}}", "image": "alpine", "all": true}`, valid: true},
+		{args: `{"quiet": true, "format": "{{.ID}}", "image": "alpine", "all": true}`, valid: false},
+		{args: `{"ports": [], "dockerfile": "Dockerfile", "all": true}`, valid: true},
+		{args: `{"ports": ["80"], "dockerfile": "Dockerfile", "all": true}`, valid: false},
+		{args: `{"image": "", "dockerfile": "Dockerfile", "paths": ["a"]}`, valid: true},
+		{args: `{"image": "alpine", "all": false}`, valid: false},
+	}
+
+	for _, tt := range tests {
+		var args map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(tt.args), &args))
+		err := schema.Validate(args, decoded)
+		if tt.valid {
+			assert.NoError(t, err, tt.args)
+		} else {
+			assert.Error(t, err, tt.args)
+		}
+	}
+}
+
+func TestValueProperty(t *testing.T) {
+	s := &Server{}
+	min := 1
+	prop := s.valueProperty(&config.Value{
+		Type: "array",
+		Items: &config.Value{
+			Type: "object",
+			Properties: map[string]*config.Value{
+				"port":  {Type: "integer", Min: &min, Required: true},
+				"host":  {Type: "string", Format: "hostname", Required: true},
+				"since": {Type: "string", Format: "date-time"},
+				"tags":  {Type: "array"},
+			},
+		},
+	})
+
+	data, err := json.Marshal(prop)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "array",
+		"items": {
+			"type": "object",
+			"properties": {
+				"port": {"type": "integer", "minimum": 1},
+				"host": {"type": "string", "format": "hostname"},
+				"since": {"type": "string", "format": "date-time"},
+				"tags": {"type": "array", "items": {"type": "string"}}
+			},
+			"required": ["host", "port"]
+		}
+	}`, string(data))
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	assert.Equal(t, "2025-06-18", negotiateVersion("2025-06-18"))
+	assert.Equal(t, "2024-11-05", negotiateVersion("2024-11-05"))
+	assert.Equal(t, "2025-06-18", negotiateVersion("1999-01-01"))
+	assert.Equal(t, "2025-06-18", negotiateVersion(""))
+}
@@ -65,24 +65,34 @@ type ToolsListResult struct {
 }
 
 type ToolInfo struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	InputSchema InputSchema `json:"inputSchema"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	InputSchema  InputSchema            `json:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
 }
 
 type InputSchema struct {
 	Type       string              `json:"type"`
 	Properties map[string]Property `json:"properties,omitempty"`
 	Required   []string            `json:"required,omitempty"`
+
+	// Argument constraints
+	OneOf []map[string]interface{} `json:"oneOf,omitempty"`
+	AnyOf []map[string]interface{} `json:"anyOf,omitempty"`
+	Not   map[string]interface{}   `json:"not,omitempty"`
+	AllOf []map[string]interface{} `json:"allOf,omitempty"`
 }
 
 type Property struct {
 	Type        string   `json:"type"`
 	Description string   `json:"description,omitempty"`
 	Default     interface{} `json:"default,omitempty"`
+	Format      string   `json:"format,omitempty"`
 	Minimum     *int     `json:"minimum,omitempty"`
 	Maximum     *int     `json:"maximum,omitempty"`
 	Items       *Property `json:"items,omitempty"`
+	Properties  map[string]Property `json:"properties,omitempty"`
+	Required    []string `json:"required,omitempty"`
 }
 
 type ToolCallParams struct {
@@ -91,13 +101,38 @@ type ToolCallParams struct {
 }
 
 type ToolCallResult struct {
-	Content []ContentItem `json:"content"`
-	IsError bool         `json:"isError,omitempty"`
+	Content           []ContentItem          `json:"content"`
+	StructuredContent interface{}            `json:"structuredContent,omitempty"`
+	IsError           bool                   `json:"isError,omitempty"`
+	Meta              map[string]interface{} `json:"_meta,omitempty"`
 }
 
 type ContentItem struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type     string            `json:"type"`
+	Text     string            `json:"text"`
+	Data     string            `json:"data,omitempty"`     // Base64 image data
+	MimeType string            `json:"mimeType,omitempty"` // MIME type of image data
+	Resource *ResourceContents `json:"resource,omitempty"` // Embedded resource
+}
+
+// MarshalJSON leaves the text field out of image and resource content
+func (c ContentItem) MarshalJSON() ([]byte, error) {
+	type plain ContentItem
+	if c.Type == "text" {
+		return json.Marshal(plain(c))
+	}
+	return json.Marshal(struct {
+		plain
+		Text string `json:"text,omitempty"`
+	}{plain(c), c.Text})
+}
+
+// ResourceContents is the content of an embedded resource
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"` // Base64 data
 }
 
 type PromptsListResult struct {
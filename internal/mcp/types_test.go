@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/charignon/umcp/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentItemJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     ContentItem
+		expected string
+	}{
+		{
+			name:     "empty text keeps text field",
+			item:     ContentItem{Type: "text"},
+			expected: `{"type": "text", "text": ""}`,
+		},
+		{
+			name:     "image",
+			item:     binaryContent("image", &executor.Binary{Data: []byte("png"), MimeType: "image/png"}),
+			expected: `{"type": "image", "data": "cG5n", "mimeType": "image/png"}`,
+		},
+		{
+			name: "resource",
+			item: binaryContent("file", &executor.Binary{Data: []byte("%PDF"), MimeType: "application/pdf", URI: "file:///tmp/a.pdf"}),
+			expected: `{"type": "resource", "resource": {
+				"uri": "file:///tmp/a.pdf", "mimeType": "application/pdf", "blob": "JVBERg=="
+			}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.item)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/charignon/umcp/internal/executor"
+)
+
+// Default histogram buckets
+var (
+	DurationBuckets   = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+	OutputSizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216}
+)
+
+// Metrics collects tool call metrics and serves them in the Prometheus
+// text exposition format. It implements executor.Tracer and
+// executor.StageTracer so it can be registered on a CommandExecutor; call
+// counts and in-flight commands are reported by the server.
+//
+// All series are labelled with the full MCP tool name (config_tool).
+type Metrics struct {
+	mu                sync.Mutex
+	calls             *counterVec
+	errors            *counterVec
+	timeouts          *counterVec
+	sandboxRejections *counterVec
+	parseFailures     *counterVec
+	inFlight          *counterVec
+	duration          *histogramVec
+	outputSize        *histogramVec
+}
+
+// New creates an empty metrics collector
+func New() *Metrics {
+	return &Metrics{
+		calls:             newCounterVec("umcp_tool_calls_total", "Total number of tool calls.", "counter"),
+		errors:            newCounterVec("umcp_tool_errors_total", "Total number of tool calls that returned an error.", "counter"),
+		timeouts:          newCounterVec("umcp_tool_timeouts_total", "Total number of commands that timed out.", "counter"),
+		sandboxRejections: newCounterVec("umcp_tool_sandbox_rejections_total", "Total number of commands rejected by the security policy.", "counter"),
+		parseFailures:     newCounterVec("umcp_tool_parse_failures_total", "Total number of outputs that failed to parse.", "counter"),
+		inFlight:          newCounterVec("umcp_tool_in_flight", "Number of tool calls currently executing.", "gauge"),
+		duration:          newHistogramVec("umcp_tool_duration_seconds", "Command execution duration in seconds.", DurationBuckets),
+		outputSize:        newHistogramVec("umcp_tool_output_bytes", "Command output size in bytes.", OutputSizeBuckets),
+	}
+}
+
+// CallStarted records the start of a tool call
+func (m *Metrics) CallStarted(tool string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls.add(tool, 1)
+	m.inFlight.add(tool, 1)
+}
+
+// CallFinished records the end of a tool call
+func (m *Metrics) CallFinished(tool string, err error) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight.add(tool, -1)
+	if err != nil {
+		m.errors.add(tool, 1)
+	}
+}
+
+// TraceCommand is a no-op; commands are observed through their stages
+func (m *Metrics) TraceCommand(command string, args []string, workingDir string, env []string) {}
+
+// TraceCommandOutput is a no-op; commands are observed through their stages
+func (m *Metrics) TraceCommandOutput(output string, exitCode int, err error) {}
+
+// TraceStage records duration, output size and failures of execution stages
+func (m *Metrics) TraceStage(stage executor.Stage) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tool := fmt.Sprintf("%s_%s", stage.Config, stage.Tool)
+	switch stage.Name {
+	case "sandbox":
+		if stage.Err != nil {
+			m.sandboxRejections.add(tool, 1)
+		}
+	case "exec":
+		m.duration.observe(tool, stage.Duration.Seconds())
+		if errors.Is(stage.Err, executor.ErrTimeout) || stage.Attributes["call_deadline_exceeded"] == true {
+			m.timeouts.add(tool, 1)
+		}
+		if size, ok := stage.Attributes["output_bytes"].(int); ok {
+			m.outputSize.observe(tool, float64(size))
+		}
+	case "parse":
+		if stage.Err != nil {
+			m.parseFailures.add(tool, 1)
+		}
+	}
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sb strings.Builder
+	for _, c := range []*counterVec{m.calls, m.errors, m.timeouts, m.sandboxRejections, m.parseFailures, m.inFlight} {
+		c.write(&sb)
+	}
+	for _, h := range []*histogramVec{m.duration, m.outputSize} {
+		h.write(&sb)
+	}
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// Handler returns an HTTP handler serving the metrics
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteTo(w)
+	})
+}
+
+// counterVec is a set of counters (or gauges) keyed by tool name
+type counterVec struct {
+	name   string
+	help   string
+	kind   string
+	values map[string]float64
+}
+
+func newCounterVec(name, help, kind string) *counterVec {
+	return &counterVec{name: name, help: help, kind: kind, values: make(map[string]float64)}
+}
+
+func (c *counterVec) add(tool string, delta float64) {
+	c.values[tool] += delta
+}
+
+func (c *counterVec) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s %s\n", c.name, c.help, c.name, c.kind)
+	for _, tool := range sortedKeys(c.values) {
+		fmt.Fprintf(sb, "%s{tool=\"%s\"} %s\n", c.name, escapeLabel(tool), formatFloat(c.values[tool]))
+	}
+}
+
+// histogramVec is a set of histograms keyed by tool name
+type histogramVec struct {
+	name    string
+	help    string
+	buckets []float64
+	series  map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // cumulative count per bucket
+	count  uint64
+	sum    float64
+}
+
+func newHistogramVec(name, help string, buckets []float64) *histogramVec {
+	return &histogramVec{name: name, help: help, buckets: buckets, series: make(map[string]*histogram)}
+}
+
+func (h *histogramVec) observe(tool string, value float64) {
+	s, ok := h.series[tool]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[tool] = s
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *histogramVec) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, tool := range sortedKeys(h.series) {
+		s := h.series[tool]
+		label := escapeLabel(tool)
+		for i, bound := range h.buckets {
+			fmt.Fprintf(sb, "%s_bucket{tool=\"%s\",le=\"%s\"} %d\n", h.name, label, formatFloat(bound), s.counts[i])
+		}
+		fmt.Fprintf(sb, "%s_bucket{tool=\"%s\",le=\"+Inf\"} %d\n", h.name, label, s.count)
+		fmt.Fprintf(sb, "%s_sum{tool=\"%s\"} %s\n", h.name, label, formatFloat(s.sum))
+		fmt.Fprintf(sb, "%s_count{tool=\"%s\"} %d\n", h.name, label, s.count)
+	}
+}
+
+// sortedKeys returns map keys in sorted order for stable output
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeLabel escapes a label value for the exposition format
+func escapeLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}
+
+// formatFloat formats a sample value
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charignon/umcp/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	m := New()
+
+	m.CallStarted("git_status")
+	m.TraceStage(executor.Stage{Name: "sandbox", Config: "git", Tool: "status"})
+	m.TraceStage(executor.Stage{
+		Name:       "exec",
+		Config:     "git",
+		Tool:       "status",
+		Duration:   200 * time.Millisecond,
+		Attributes: map[string]interface{}{"output_bytes": 100},
+	})
+	m.TraceStage(executor.Stage{Name: "parse", Config: "git", Tool: "status", Err: errors.New("bad json")})
+	m.CallFinished("git_status", nil)
+
+	m.CallStarted("git_log")
+	m.TraceStage(executor.Stage{
+		Name:     "exec",
+		Config:   "git",
+		Tool:     "log",
+		Duration: 30 * time.Second,
+		Err:      fmt.Errorf("%w after 30s", executor.ErrTimeout),
+	})
+	m.CallFinished("git_log", errors.New("timed out"))
+
+	// The call deadline killing a command counts as a timeout too
+	m.CallStarted("git_fetch")
+	m.TraceStage(executor.Stage{
+		Name:       "exec",
+		Config:     "git",
+		Tool:       "fetch",
+		Err:        errors.New("signal: killed"),
+		Attributes: map[string]interface{}{"call_deadline_exceeded": true},
+	})
+	m.CallFinished("git_fetch", errors.New("call deadline exceeded"))
+
+	m.CallStarted("git_push")
+	m.TraceStage(executor.Stage{Name: "sandbox", Config: "git", Tool: "push", Err: errors.New("blocked")})
+
+	var sb strings.Builder
+	_, err := m.WriteTo(&sb)
+	require.NoError(t, err)
+	out := sb.String()
+
+	for _, line := range []string{
+		"# TYPE umcp_tool_calls_total counter",
+		`umcp_tool_calls_total{tool="git_status"} 1`,
+		`umcp_tool_errors_total{tool="git_log"} 1`,
+		`umcp_tool_timeouts_total{tool="git_log"} 1`,
+		`umcp_tool_timeouts_total{tool="git_fetch"} 1`,
+		`umcp_tool_sandbox_rejections_total{tool="git_push"} 1`,
+		`umcp_tool_parse_failures_total{tool="git_status"} 1`,
+		"# TYPE umcp_tool_in_flight gauge",
+		`umcp_tool_in_flight{tool="git_push"} 1`,
+		`umcp_tool_in_flight{tool="git_status"} 0`,
+		"# TYPE umcp_tool_duration_seconds histogram",
+		`umcp_tool_duration_seconds_bucket{tool="git_status",le="0.1"} 0`,
+		`umcp_tool_duration_seconds_bucket{tool="git_status",le="0.25"} 1`,
+		`umcp_tool_duration_seconds_bucket{tool="git_log",le="+Inf"} 1`,
+		`umcp_tool_duration_seconds_sum{tool="git_status"} 0.2`,
+		`umcp_tool_output_bytes_bucket{tool="git_status",le="256"} 1`,
+		`umcp_tool_output_bytes_count{tool="git_status"} 1`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+
+	assert.NotContains(t, out, `umcp_tool_errors_total{tool="git_status"}`)
+	assert.NotContains(t, out, `umcp_tool_sandbox_rejections_total{tool="git_status"}`)
+}
+
+func TestMetricsHandler(t *testing.T) {
+	m := New()
+	m.CallStarted(`odd"name`)
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+	assert.Contains(t, string(body), `umcp_tool_calls_total{tool="odd\"name"} 1`)
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	m.CallStarted("x")
+	m.CallFinished("x", errors.New("ignored"))
+	m.TraceStage(executor.Stage{Name: "exec"})
+}
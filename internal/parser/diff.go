@@ -0,0 +1,330 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charignon/umcp/internal/config"
+)
+
+// hunkHeaderPattern matches "@@ -start,count +start,count @@ section"
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// diffFile is one file of a unified diff
+type diffFile struct {
+	OldPath      string      `json:"old_path,omitempty"`
+	NewPath      string      `json:"new_path,omitempty"`
+	Status       string      `json:"status"` // added, deleted, modified, renamed or copied
+	OldMode      string      `json:"old_mode,omitempty"`
+	NewMode      string      `json:"new_mode,omitempty"`
+	Similarity   *int        `json:"similarity,omitempty"`
+	Binary       bool        `json:"binary,omitempty"`
+	Additions    int         `json:"additions"`
+	Deletions    int         `json:"deletions"`
+	Hunks        []*diffHunk `json:"hunks,omitempty"`
+	HunksOmitted int         `json:"hunks_omitted,omitempty"`
+	LinesOmitted int         `json:"lines_omitted,omitempty"`
+}
+
+// diffHunk is a hunk of changes. Lines keep their " ", "+", "-" or "\"
+// prefix.
+type diffHunk struct {
+	Header   string   `json:"header"`
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Section  string   `json:"section,omitempty"`
+	Lines    []string `json:"lines"`
+}
+
+// diffStats summarizes a whole diff
+type diffStats struct {
+	Files     int `json:"files"`
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+}
+
+// diffResult is the JSON form of a parsed diff
+type diffResult struct {
+	Preamble string      `json:"preamble,omitempty"` // Text before the first file, such as a commit header
+	Stats    diffStats   `json:"stats"`
+	Files    []*diffFile `json:"files"`
+}
+
+// parseDiff parses unified diffs (git diff, git show, diff -u) into files,
+// hunks and lines with per-file statistics
+func parseDiff(output string, outputCfg *config.Output) (string, error) {
+	result, err := decodeDiff(output)
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range result.Files {
+		result.Stats.Additions += file.Additions
+		result.Stats.Deletions += file.Deletions
+		limitDiffFile(file, outputCfg)
+	}
+	result.Stats.Files = len(result.Files)
+
+	// Round-trip through JSON so jq filters see plain maps and slices
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode diff: %w", err)
+	}
+	var data interface{}
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return "", fmt.Errorf("failed to encode diff: %w", err)
+	}
+	return formatOutput(data, outputCfg)
+}
+
+// decodeDiff splits a diff into files and hunks
+func decodeDiff(output string) (*diffResult, error) {
+	result := &diffResult{Files: []*diffFile{}}
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+
+	var preamble []string
+	var file *diffFile
+	var hunk *diffHunk
+	oldLeft, newLeft := 0, 0
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// Inside a hunk, the line counts decide where it ends
+		if hunk != nil && (oldLeft > 0 || newLeft > 0 || strings.HasPrefix(line, `\`)) {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				file.Additions++
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				file.Deletions++
+				oldLeft--
+			case strings.HasPrefix(line, " "), line == "":
+				oldLeft--
+				newLeft--
+			case strings.HasPrefix(line, `\`):
+			default:
+				return nil, fmt.Errorf("invalid diff: unexpected line %d in hunk: %q", i+1, line)
+			}
+			hunk.Lines = append(hunk.Lines, line)
+			continue
+		}
+		hunk = nil
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			file = newGitDiffFile(line)
+			result.Files = append(result.Files, file)
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			oldPath := diffPath(line[4:], "a/")
+			newPath := diffPath(lines[i+1][4:], "b/")
+			i++
+			if file == nil || len(file.Hunks) > 0 || file.Binary {
+				// A plain unified diff without a "diff --git" line
+				file = &diffFile{Status: "modified"}
+				result.Files = append(result.Files, file)
+			}
+			if oldPath == "" {
+				file.Status = "added"
+			} else {
+				file.OldPath = oldPath
+			}
+			if newPath == "" {
+				file.Status = "deleted"
+			} else {
+				file.NewPath = newPath
+			}
+
+		case strings.HasPrefix(line, "@@ "):
+			match := hunkHeaderPattern.FindStringSubmatch(line)
+			if match == nil || file == nil {
+				return nil, fmt.Errorf("invalid diff: bad hunk header on line %d: %q", i+1, line)
+			}
+			hunk = &diffHunk{
+				Header:   line,
+				OldStart: atoiDefault(match[1], 0),
+				OldLines: atoiDefault(match[2], 1),
+				NewStart: atoiDefault(match[3], 0),
+				NewLines: atoiDefault(match[4], 1),
+				Section:  match[5],
+				Lines:    []string{},
+			}
+			oldLeft, newLeft = hunk.OldLines, hunk.NewLines
+			file.Hunks = append(file.Hunks, hunk)
+
+		case file != nil && parseDiffHeader(file, line):
+
+		case file == nil:
+			preamble = append(preamble, line)
+		}
+	}
+
+	if len(result.Files) == 0 {
+		if strings.TrimSpace(output) != "" {
+			return nil, fmt.Errorf("invalid diff: no file changes found")
+		}
+		return result, nil
+	}
+	result.Preamble = strings.TrimSpace(strings.Join(preamble, "\n"))
+	return result, nil
+}
+
+// newGitDiffFile starts a file from a "diff --git a/x b/y" line
+func newGitDiffFile(line string) *diffFile {
+	file := &diffFile{Status: "modified"}
+	paths := strings.TrimPrefix(line, "diff --git ")
+	if strings.HasPrefix(paths, `"`) {
+		if old, rest, ok := cutQuoted(paths); ok {
+			file.OldPath = strings.TrimPrefix(old, "a/")
+			file.NewPath = diffPath(strings.TrimSpace(rest), "b/")
+		}
+		return file
+	}
+	if idx := strings.LastIndex(paths, " b/"); idx >= 0 {
+		file.OldPath = strings.TrimPrefix(paths[:idx], "a/")
+		file.NewPath = paths[idx+3:]
+	}
+	return file
+}
+
+// parseDiffHeader applies a git extended header line to file and reports
+// whether the line was one
+func parseDiffHeader(file *diffFile, line string) bool {
+	switch {
+	case strings.HasPrefix(line, "new file mode "):
+		file.Status = "added"
+		file.NewMode = strings.TrimPrefix(line, "new file mode ")
+		file.OldPath = ""
+	case strings.HasPrefix(line, "deleted file mode "):
+		file.Status = "deleted"
+		file.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+		file.NewPath = ""
+	case strings.HasPrefix(line, "old mode "):
+		file.OldMode = strings.TrimPrefix(line, "old mode ")
+	case strings.HasPrefix(line, "new mode "):
+		file.NewMode = strings.TrimPrefix(line, "new mode ")
+	case strings.HasPrefix(line, "rename from "):
+		file.Status = "renamed"
+		file.OldPath = diffPath(strings.TrimPrefix(line, "rename from "), "")
+	case strings.HasPrefix(line, "rename to "):
+		file.Status = "renamed"
+		file.NewPath = diffPath(strings.TrimPrefix(line, "rename to "), "")
+	case strings.HasPrefix(line, "copy from "):
+		file.Status = "copied"
+		file.OldPath = diffPath(strings.TrimPrefix(line, "copy from "), "")
+	case strings.HasPrefix(line, "copy to "):
+		file.Status = "copied"
+		file.NewPath = diffPath(strings.TrimPrefix(line, "copy to "), "")
+	case strings.HasPrefix(line, "similarity index "), strings.HasPrefix(line, "dissimilarity index "):
+		value := line[strings.LastIndexByte(line, ' ')+1:]
+		if n, err := strconv.Atoi(strings.TrimSuffix(value, "%")); err == nil {
+			file.Similarity = &n
+		}
+	case strings.HasPrefix(line, "index "):
+		// "index abc..def 100644" carries the mode of unchanged-mode files
+		if fields := strings.Fields(line); len(fields) == 3 && file.OldMode == "" && file.NewMode == "" {
+			file.OldMode, file.NewMode = fields[2], fields[2]
+		}
+	case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+		file.Binary = true
+	case file.Binary && (strings.HasPrefix(line, "literal ") || strings.HasPrefix(line, "delta ") || line == ""):
+		// Body of a binary patch
+	case file.Binary && len(line) > 0 && isBase85Line(line):
+	default:
+		return false
+	}
+	return true
+}
+
+// limitDiffFile applies the stat_only, max_hunks and max_lines options
+func limitDiffFile(file *diffFile, outputCfg *config.Output) {
+	if outputCfg.StatOnly {
+		file.Hunks = nil
+		return
+	}
+
+	if outputCfg.MaxHunks > 0 && len(file.Hunks) > outputCfg.MaxHunks {
+		file.HunksOmitted = len(file.Hunks) - outputCfg.MaxHunks
+		file.Hunks = file.Hunks[:outputCfg.MaxHunks]
+	}
+
+	if outputCfg.MaxLines > 0 {
+		budget := outputCfg.MaxLines
+		for i, hunk := range file.Hunks {
+			if budget <= 0 {
+				for _, omitted := range file.Hunks[i:] {
+					file.LinesOmitted += len(omitted.Lines)
+				}
+				file.HunksOmitted += len(file.Hunks) - i
+				file.Hunks = file.Hunks[:i]
+				break
+			}
+			if len(hunk.Lines) > budget {
+				file.LinesOmitted += len(hunk.Lines) - budget
+				hunk.Lines = hunk.Lines[:budget]
+			}
+			budget -= len(hunk.Lines)
+		}
+	}
+}
+
+// diffPath strips the a/ or b/ prefix and any quoting from a path in a
+// diff header. /dev/null becomes the empty string.
+func diffPath(path, prefix string) string {
+	path = strings.TrimRight(path, "\t")
+	if idx := strings.IndexByte(path, '\t'); idx >= 0 {
+		// diff -u appends a timestamp after a tab
+		path = path[:idx]
+	}
+	if strings.HasPrefix(path, `"`) {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			path = unquoted
+		}
+	}
+	if path == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// cutQuoted splits a leading quoted string from s
+func cutQuoted(s string) (string, string, bool) {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == '"' {
+			unquoted, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", false
+			}
+			return unquoted, s[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// isBase85Line reports whether line looks like a line of a git binary
+// patch: a length character followed by base85 data
+func isBase85Line(line string) bool {
+	c := line[0]
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
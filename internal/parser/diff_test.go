@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gitShowOutput = `commit 3ad5319c0ffee
+Author: Dev <dev@example.com>
+Date:   Mon Oct 5 10:00:00 2026 +0000
+
+    Update greeting
+
+diff --git a/hello.go b/hello.go
+index 1111111..2222222 100644
+--- a/hello.go
++++ b/hello.go
+@@ -1,4 +1,4 @@ package main
+ func main() {
+-	println("hi")
++	println("hello")
+ }
+
+@@ -10,2 +10,3 @@ func other() {
+ 	x := 1
++	y := 2
+ 	return
+\ No newline at end of file
+diff --git a/old name.txt b/new name.txt
+similarity index 90%
+rename from old name.txt
+rename to new name.txt
+index 3333333..4444444 100644
+--- a/old name.txt
++++ b/new name.txt
+@@ -1 +1 @@
+-a
++b
+diff --git a/script.sh b/script.sh
+old mode 100644
+new mode 100755
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..5555555
+Binary files /dev/null and b/logo.png differ
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 6666666..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-one
+-two
+`
+
+func TestParseDiffGit(t *testing.T) {
+	result, err := parseDiff(gitShowOutput, &config.Output{Type: "diff"})
+	require.NoError(t, err)
+
+	expected := `{
+		"preamble": "commit 3ad5319c0ffee\nAuthor: Dev <dev@example.com>\nDate:   Mon Oct 5 10:00:00 2026 +0000\n\n    Update greeting",
+		"stats": {"files": 5, "additions": 3, "deletions": 4},
+		"files": [
+			{
+				"old_path": "hello.go", "new_path": "hello.go", "status": "modified",
+				"old_mode": "100644", "new_mode": "100644",
+				"additions": 2, "deletions": 1,
+				"hunks": [
+					{
+						"header": "@@ -1,4 +1,4 @@ package main",
+						"old_start": 1, "old_lines": 4, "new_start": 1, "new_lines": 4,
+						"section": "package main",
+						"lines": [" func main() {", "-\tprintln(\"hi\")", "+\tprintln(\"hello\")", " }", ""]
+					},
+					{
+						"header": "@@ -10,2 +10,3 @@ func other() {",
+						"old_start": 10, "old_lines": 2, "new_start": 10, "new_lines": 3,
+						"section": "func other() {",
+						"lines": [" \tx := 1", "+\ty := 2", " \treturn", "\\ No newline at end of file"]
+					}
+				]
+			},
+			{
+				"old_path": "old name.txt", "new_path": "new name.txt", "status": "renamed",
+				"old_mode": "100644", "new_mode": "100644", "similarity": 90,
+				"additions": 1, "deletions": 1,
+				"hunks": [
+					{
+						"header": "@@ -1 +1 @@",
+						"old_start": 1, "old_lines": 1, "new_start": 1, "new_lines": 1,
+						"lines": ["-a", "+b"]
+					}
+				]
+			},
+			{
+				"old_path": "script.sh", "new_path": "script.sh", "status": "modified",
+				"old_mode": "100644", "new_mode": "100755",
+				"additions": 0, "deletions": 0
+			},
+			{
+				"new_path": "logo.png", "status": "added", "new_mode": "100644",
+				"binary": true, "additions": 0, "deletions": 0
+			},
+			{
+				"old_path": "gone.txt", "status": "deleted", "old_mode": "100644",
+				"additions": 0, "deletions": 2,
+				"hunks": [
+					{
+						"header": "@@ -1,2 +0,0 @@",
+						"old_start": 1, "old_lines": 2, "new_start": 0, "new_lines": 0,
+						"lines": ["-one", "-two"]
+					}
+				]
+			}
+		]
+	}`
+	assert.JSONEq(t, expected, result)
+}
+
+func TestParseDiffUnified(t *testing.T) {
+	input := "--- config.old\t2026-10-01 12:00:00\n" +
+		"+++ config.new\t2026-10-02 12:00:00\n" +
+		"@@ -1,2 +1,2 @@\n" +
+		" name = umcp\n" +
+		"-port = 80\n" +
+		"+port = 8080\n" +
+		"--- \"quoted\\tname\"\n" +
+		"+++ \"quoted\\tname\"\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+--- not a header\n"
+
+	result, err := parseDiff(input, &config.Output{Type: "diff", JQ: ".files"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{
+			"old_path": "config.old", "new_path": "config.new", "status": "modified",
+			"additions": 1, "deletions": 1,
+			"hunks": [{
+				"header": "@@ -1,2 +1,2 @@", "old_start": 1, "old_lines": 2, "new_start": 1, "new_lines": 2,
+				"lines": [" name = umcp", "-port = 80", "+port = 8080"]
+			}]
+		},
+		{
+			"old_path": "quoted\tname", "new_path": "quoted\tname", "status": "modified",
+			"additions": 1, "deletions": 0,
+			"hunks": [{
+				"header": "@@ -0,0 +1 @@", "old_start": 0, "old_lines": 0, "new_start": 1, "new_lines": 1,
+				"lines": ["+--- not a header"]
+			}]
+		}
+	]`, result)
+}
+
+func TestParseDiffLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   config.Output
+		jq       string
+		expected string
+	}{
+		{
+			name:     "stat only",
+			output:   config.Output{StatOnly: true},
+			jq:       ".files[0]",
+			expected: `{"old_path": "hello.go", "new_path": "hello.go", "status": "modified", "old_mode": "100644", "new_mode": "100644", "additions": 2, "deletions": 1}`,
+		},
+		{
+			name:     "max hunks",
+			output:   config.Output{MaxHunks: 1},
+			jq:       ".files[0].hunks_omitted",
+			expected: `1`,
+		},
+		{
+			name:   "max lines truncates and drops hunks",
+			output: config.Output{MaxLines: 3},
+			jq:     ".files[0]",
+			expected: `{
+				"old_path": "hello.go", "new_path": "hello.go", "status": "modified",
+				"old_mode": "100644", "new_mode": "100644",
+				"additions": 2, "deletions": 1,
+				"hunks": [{
+					"header": "@@ -1,4 +1,4 @@ package main",
+					"old_start": 1, "old_lines": 4, "new_start": 1, "new_lines": 4,
+					"section": "package main",
+					"lines": [" func main() {", "-\tprintln(\"hi\")", "+\tprintln(\"hello\")"]
+				}],
+				"hunks_omitted": 1, "lines_omitted": 6
+			}`,
+		},
+		{
+			name:     "stats cover omitted lines",
+			output:   config.Output{MaxLines: 1},
+			jq:       ".stats",
+			expected: `{"files": 5, "additions": 3, "deletions": 4}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.output.Type = "diff"
+			tt.output.JQ = tt.jq
+			result, err := parseDiff(gitShowOutput, &tt.output)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParseDiffEmptyAndInvalid(t *testing.T) {
+	result, err := parseDiff("", &config.Output{Type: "diff"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"stats": {"files": 0, "additions": 0, "deletions": 0}, "files": []}`, result)
+
+	_, err = parseDiff("fatal: not a git repository\n", &config.Output{Type: "diff"})
+	assert.Error(t, err)
+
+	_, err = parseDiff("diff --git a/x b/x\n@@ -1,2 +1,2 @@\n x\n?oops\n", &config.Output{Type: "diff"})
+	assert.Error(t, err)
+}
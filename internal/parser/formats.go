@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charignon/umcp/internal/jq"
+	"gopkg.in/yaml.v3"
+)
+
+// parseYAML parses YAML output. Multi-document streams become an array.
+func parseYAML(output string, filter *jq.Filter) (string, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(output))
+
+	var docs []interface{}
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid YAML: %w", err)
+		}
+		docs = append(docs, jq.Normalize(doc))
+	}
+
+	var data interface{}
+	switch len(docs) {
+	case 0:
+		data = nil
+	case 1:
+		data = docs[0]
+	default:
+		data = docs
+	}
+	return formatJSON(data, filter)
+}
+
+// parseNDJSON parses newline-delimited JSON into an array of values
+func parseNDJSON(output string, filter *jq.Filter) (string, error) {
+	values := []interface{}{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), len(output)+1)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(line), &value); err != nil {
+			return "", fmt.Errorf("invalid JSON on line %d: %w", lineNum, err)
+		}
+		values = append(values, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+
+	return formatJSON(values, filter)
+}
+
+// parseLogfmt parses logfmt lines (key=value key2="quoted value") into an
+// array of objects. A key without a value is treated as true.
+func parseLogfmt(output string, filter *jq.Filter) (string, error) {
+	records := []interface{}{}
+
+	for lineNum, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		record, err := parseLogfmtLine(line)
+		if err != nil {
+			return "", fmt.Errorf("invalid logfmt on line %d: %w", lineNum+1, err)
+		}
+		records = append(records, record)
+	}
+
+	return formatJSON(records, filter)
+}
+
+// parseLogfmtLine parses a single logfmt line
+func parseLogfmtLine(line string) (map[string]interface{}, error) {
+	record := make(map[string]interface{})
+	i := 0
+	for {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		if i >= len(line) {
+			return record, nil
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '\t' {
+			if line[i] == '"' {
+				return nil, fmt.Errorf("unexpected quote in key at column %d", i+1)
+			}
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, fmt.Errorf("missing key at column %d", start+1)
+		}
+
+		if i >= len(line) || line[i] != '=' {
+			record[key] = true
+			continue
+		}
+		i++ // skip '='
+
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("unterminated quoted value for key %s", key)
+			}
+			value, err := unquoteString(line[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted value for key %s: %w", key, err)
+			}
+			record[key] = value
+			i = end + 1
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		record[key] = line[start:i]
+	}
+}
+
+// parseKeyValue parses "key: value" or "key=value" lines into an object.
+// Without an explicit separator each line is split at its first "=" or ":".
+// Repeated keys collect their values into an array.
+func parseKeyValue(output string, separator string, filter *jq.Filter) (string, error) {
+	result := make(map[string]interface{})
+
+	for lineNum, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		idx := -1
+		sepLen := len(separator)
+		if separator != "" {
+			idx = strings.Index(line, separator)
+		} else {
+			idx = strings.IndexAny(line, "=:")
+			sepLen = 1
+		}
+		if idx <= 0 {
+			return "", fmt.Errorf("invalid key/value on line %d: %q", lineNum+1, line)
+		}
+
+		key := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+sepLen:])
+		if key == "" {
+			return "", fmt.Errorf("invalid key/value on line %d: %q", lineNum+1, line)
+		}
+
+		switch existing := result[key].(type) {
+		case nil:
+			result[key] = value
+		case []interface{}:
+			result[key] = append(existing, value)
+		default:
+			result[key] = []interface{}{existing, value}
+		}
+	}
+
+	return formatJSON(result, filter)
+}
+
+// unquoteString decodes a double-quoted string with JSON-style escapes
+func unquoteString(quoted string) (string, error) {
+	var value string
+	if err := json.Unmarshal([]byte(quoted), &value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		jq       string
+		expected string
+	}{
+		{
+			name: "mapping",
+			input: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 3
+  ports: [80, 443]
+`,
+			expected: `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web", "labels": {"app": "web"}}, "spec": {"replicas": 3, "ports": [80, 443]}}`,
+		},
+		{
+			name:     "multiple documents",
+			input:    "name: a\n---\nname: b\n",
+			expected: `[{"name": "a"}, {"name": "b"}]`,
+		},
+		{
+			name:     "non-string keys",
+			input:    "1: one\ntrue: yes\n",
+			expected: `{"1": "one", "true": "yes"}`,
+		},
+		{
+			name:     "with jq",
+			input:    "items:\n  - name: a\n  - name: b\n",
+			jq:       ".items[].name",
+			expected: `["a", "b"]`,
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: `null`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseYAML(tt.input, mustParseJQ(t, tt.jq))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+
+	_, err := parseYAML("key: [unclosed", nil)
+	assert.Error(t, err)
+}
+
+func TestParseNDJSON(t *testing.T) {
+	input := `{"status": "start", "id": "abc"}
+
+{"status": "die", "id": "abc", "exitCode": 1}
+`
+	result, err := parseNDJSON(input, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"status": "start", "id": "abc"}, {"status": "die", "id": "abc", "exitCode": 1}]`, result)
+
+	result, err = parseNDJSON(input, mustParseJQ(t, `.[] | select(.status == "die") | .exitCode`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `[1]`, result)
+
+	result, err = parseNDJSON("", nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, result)
+
+	_, err = parseNDJSON("{\"ok\": true}\nnot json\n", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestParseLogfmt(t *testing.T) {
+	input := `level=info msg="server started" port=8080
+level=error msg="connection refused: \"db\"" retry
+`
+	result, err := parseLogfmt(input, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"level": "info", "msg": "server started", "port": "8080"},
+		{"level": "error", "msg": "connection refused: \"db\"", "retry": true}
+	]`, result)
+
+	result, err = parseLogfmt(input, mustParseJQ(t, ".[1].msg"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `"connection refused: \"db\""`, result)
+
+	for _, bad := range []string{`msg="unterminated`, `=value`, `k"ey=1`} {
+		_, err := parseLogfmt(bad, nil)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseKeyValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		separator string
+		expected  string
+	}{
+		{
+			name:     "git config",
+			input:    "user.name=Jane Doe\nremote.origin.url=https://example.com/repo.git\nremote.origin.fetch=a\nremote.origin.fetch=b\n",
+			expected: `{"user.name": "Jane Doe", "remote.origin.url": "https://example.com/repo.git", "remote.origin.fetch": ["a", "b"]}`,
+		},
+		{
+			name:     "colon separated",
+			input:    "Name: web\nStatus: running = yes\n",
+			expected: `{"Name": "web", "Status": "running = yes"}`,
+		},
+		{
+			name:      "explicit separator",
+			input:     "Id => 42\nUrl => http://x:80\n",
+			separator: "=>",
+			expected:  `{"Id": "42", "Url": "http://x:80"}`,
+		},
+		{
+			name:     "empty value",
+			input:    "Description=\n",
+			expected: `{"Description": ""}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseKeyValue(tt.input, tt.separator, nil)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+
+	_, err := parseKeyValue("Name=web\nno separator here\n", "", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestParseOutputStructuredFormats(t *testing.T) {
+	tests := []struct {
+		output   config.Output
+		input    string
+		expected string
+	}{
+		{config.Output{Type: "yaml", JQ: ".name"}, "name: web\n", `"web"`},
+		{config.Output{Type: "toml", JQ: ".server.port"}, "[server]\nport = 8080\n", `8080`},
+		{config.Output{Type: "ndjson", JQ: "length"}, "{}\n{}\n", `2`},
+		{config.Output{Type: "logfmt", JQ: ".[0].a"}, "a=1 b=2\n", `"1"`},
+		{config.Output{Type: "keyvalue", Separator: ":", JQ: ".ActiveState"}, "ActiveState: active\n", `"active"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output.Type, func(t *testing.T) {
+			result, err := ParseOutput(tt.input, &tt.output)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+}
@@ -3,27 +3,49 @@ package parser
 import (
 	"encoding/csv"
 	"encoding/json"
-	"encoding/xml"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/charignon/umcp/internal/config"
+	"github.com/charignon/umcp/internal/jq"
 )
 
 // ParseOutput parses command output according to the output configuration
 func ParseOutput(output string, outputCfg *config.Output) (string, error) {
+	filter, err := outputCfg.JQFilter()
+	if err != nil {
+		return "", err
+	}
+
 	switch outputCfg.Type {
 	case "json":
-		return parseJSON(output, outputCfg.JQ)
+		return parseJSON(output, filter)
 	case "lines":
 		return parseLines(output)
 	case "regex":
-		return parseRegex(output, outputCfg.Pattern, outputCfg.Groups)
+		return parseRegex(output, outputCfg)
 	case "csv":
-		return parseCSV(output)
+		return parseCSV(output, outputCfg)
 	case "xml":
-		return parseXML(output)
+		return parseXML(output, outputCfg.Select, filter)
+	case "yaml":
+		return parseYAML(output, filter)
+	case "toml":
+		return parseTOML(output, filter)
+	case "ndjson":
+		return parseNDJSON(output, filter)
+	case "logfmt":
+		return parseLogfmt(output, filter)
+	case "keyvalue":
+		return parseKeyValue(output, outputCfg.Separator, filter)
+	case "table":
+		return parseTable(output, outputCfg.Columns, filter)
+	case "diff":
+		return parseDiff(output, outputCfg)
 	case "raw":
 		fallthrough
 	default:
@@ -32,18 +54,28 @@ func ParseOutput(output string, outputCfg *config.Output) (string, error) {
 }
 
 // parseJSON parses JSON output and optionally applies JQ filter
-func parseJSON(output string, jqFilter string) (string, error) {
+func parseJSON(output string, filter *jq.Filter) (string, error) {
 	// First validate that it's valid JSON
 	var data interface{}
 	if err := json.Unmarshal([]byte(output), &data); err != nil {
 		return "", fmt.Errorf("invalid JSON: %w", err)
 	}
 
-	// TODO: Implement JQ filtering if needed
-	// For now, just pretty-print the JSON
+	return formatJSON(data, filter)
+}
+
+// formatJSON applies an optional jq filter and pretty-prints the result
+func formatJSON(data interface{}, filter *jq.Filter) (string, error) {
+	if filter != nil {
+		var err error
+		if data, err = filter.Apply(data); err != nil {
+			return "", err
+		}
+	}
+
 	pretty, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
-		return output, nil
+		return "", fmt.Errorf("failed to encode output: %w", err)
 	}
 
 	return string(pretty), nil
@@ -69,55 +101,176 @@ func parseLines(output string) (string, error) {
 	return string(data), nil
 }
 
-// parseRegex applies regex pattern and extracts groups
-func parseRegex(output string, pattern string, groups []config.Group) (string, error) {
-	if pattern == "" {
+// formatOutput is formatJSON with the output's jq filter
+func formatOutput(data interface{}, outputCfg *config.Output) (string, error) {
+	filter, err := outputCfg.JQFilter()
+	if err != nil {
+		return "", err
+	}
+	return formatJSON(data, filter)
+}
+
+// parseRegex applies the regex pattern, or several named patterns, and
+// extracts their capture groups. Named groups ((?P<name>...)) are used
+// automatically; other groups take their names from config.Group by
+// position. With a record separator each record yields one object merging
+// the first match of every pattern.
+func parseRegex(output string, outputCfg *config.Output) (string, error) {
+	if outputCfg.Pattern == "" && len(outputCfg.Patterns) == 0 {
 		return "", fmt.Errorf("regex pattern is required")
 	}
 
-	re, err := regexp.Compile(pattern)
+	re, err := outputCfg.Regexp()
 	if err != nil {
 		return "", fmt.Errorf("invalid regex pattern: %w", err)
 	}
+	separator, err := outputCfg.RecordSeparatorRegexp()
+	if err != nil {
+		return "", fmt.Errorf("invalid record separator: %w", err)
+	}
 
-	var results []map[string]interface{}
+	if separator != nil {
+		results := []interface{}{}
+		for _, record := range splitRecords(output, separator) {
+			obj := make(map[string]interface{})
+			matched := false
+			if re != nil {
+				if match := re.FindStringSubmatch(record); match != nil {
+					mergeMatch(obj, re, match, outputCfg.Groups)
+					matched = true
+				}
+			}
+			for i := range outputCfg.Patterns {
+				p := &outputCfg.Patterns[i]
+				pre, err := p.Regexp()
+				if err != nil {
+					return "", fmt.Errorf("invalid regex pattern %s: %w", p.Name, err)
+				}
+				if match := pre.FindStringSubmatch(record); match != nil {
+					mergeMatch(obj, pre, match, p.Groups)
+					matched = true
+				}
+			}
+			if matched {
+				results = append(results, obj)
+			}
+		}
+		return formatOutput(singleResult(results, outputCfg.Single), outputCfg)
+	}
+
+	if re != nil {
+		results := findMatches(output, re, outputCfg.Groups)
+		return formatOutput(singleResult(results, outputCfg.Single), outputCfg)
+	}
+
+	combined := make(map[string]interface{}, len(outputCfg.Patterns))
+	for i := range outputCfg.Patterns {
+		p := &outputCfg.Patterns[i]
+		pre, err := p.Regexp()
+		if err != nil {
+			return "", fmt.Errorf("invalid regex pattern %s: %w", p.Name, err)
+		}
+		combined[p.Name] = singleResult(findMatches(output, pre, p.Groups), p.Single)
+	}
+	return formatOutput(combined, outputCfg)
+}
 
-	// Find all matches
-	matches := re.FindAllStringSubmatch(output, -1)
-	for _, match := range matches {
-		result := make(map[string]interface{})
+// findMatches returns an object for every match of re in output
+func findMatches(output string, re *regexp.Regexp, groups []config.Group) []interface{} {
+	results := []interface{}{}
+	for _, match := range re.FindAllStringSubmatch(output, -1) {
+		obj := make(map[string]interface{})
+		mergeMatch(obj, re, match, groups)
+		results = append(results, obj)
+	}
+	return results
+}
 
-		// If we have named groups, use them
-		if len(groups) > 0 {
-			for i, group := range groups {
-				if i+1 < len(match) {
-					result[group.Name] = convertType(match[i+1], group.Type)
+// mergeMatch adds the capture groups of a match to obj
+func mergeMatch(obj map[string]interface{}, re *regexp.Regexp, match []string, groups []config.Group) {
+	names := re.SubexpNames()
+	for i := 1; i < len(match); i++ {
+		name := names[i]
+		typeName := ""
+		if name == "" && i-1 < len(groups) {
+			// Unnamed groups map to config groups by position
+			name = groups[i-1].Name
+			typeName = groups[i-1].Type
+		} else if name != "" {
+			for _, group := range groups {
+				if group.Name == name {
+					typeName = group.Type
 				}
 			}
-		} else {
-			// Otherwise, use numbered groups
-			for i, submatch := range match {
-				if i > 0 { // Skip the full match at index 0
-					result[fmt.Sprintf("group%d", i)] = submatch
-				}
+		}
+		if name == "" {
+			if len(groups) > 0 {
+				continue
 			}
+			name = fmt.Sprintf("group%d", i)
 		}
+		obj[name] = convertType(match[i], typeName)
+	}
+}
 
-		results = append(results, result)
+// splitRecords splits output before every match of separator, dropping
+// blank records
+func splitRecords(output string, separator *regexp.Regexp) []string {
+	var records []string
+	start := 0
+	for _, loc := range separator.FindAllStringIndex(output, -1) {
+		if loc[0] == loc[1] {
+			// Empty matches cannot start a record
+			continue
+		}
+		if loc[0] > start {
+			records = append(records, output[start:loc[0]])
+		}
+		start = loc[0]
 	}
+	records = append(records, output[start:])
 
-	data, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		return output, err
+	filtered := records[:0]
+	for _, record := range records {
+		if strings.TrimSpace(record) != "" {
+			filtered = append(filtered, record)
+		}
 	}
+	return filtered
+}
 
-	return string(data), nil
+// singleResult returns the first result, or nil if there is none, when
+// single is set, and all results otherwise
+func singleResult(results []interface{}, single bool) interface{} {
+	if !single {
+		return results
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	return results[0]
 }
 
-// parseCSV parses CSV output
-func parseCSV(output string) (string, error) {
+// parseCSV parses CSV output into an array of objects. Rows may have a
+// different number of fields than the header: missing fields are null and
+// extra fields are collected under "_extra".
+func parseCSV(output string, outputCfg *config.Output) (string, error) {
+	delimiter, err := outputCfg.DelimiterRune()
+	if err != nil {
+		return "", err
+	}
+	comment, err := outputCfg.CommentRune()
+	if err != nil {
+		return "", err
+	}
+
 	reader := csv.NewReader(strings.NewReader(output))
-	reader.TrimLeadingSpace = true
+	reader.Comma = delimiter
+	reader.Comment = comment
+	reader.LazyQuotes = outputCfg.LazyQuotes
+	// Trimming leading space would swallow empty fields between tabs
+	reader.TrimLeadingSpace = !unicode.IsSpace(delimiter)
+	reader.FieldsPerRecord = -1
 
 	records, err := reader.ReadAll()
 	if err != nil {
@@ -126,64 +279,76 @@ func parseCSV(output string) (string, error) {
 
 	// Convert to JSON array of objects
 	if len(records) == 0 {
-		return "[]", nil
+		return formatOutput([]interface{}{}, outputCfg)
 	}
 
-	// Use first row as headers
-	headers := records[0]
-	var results []map[string]string
+	var headers []string
+	types := make(map[int]string)
+	if outputCfg.Header() {
+		// Use first row as headers; columns are type hints matched by name
+		headers = records[0]
+		records = records[1:]
+		for i, header := range headers {
+			for _, col := range outputCfg.Columns {
+				if strings.EqualFold(col.Name, header) {
+					types[i] = col.Type
+				}
+			}
+		}
+	} else if len(outputCfg.Columns) > 0 {
+		for i, col := range outputCfg.Columns {
+			headers = append(headers, col.Name)
+			types[i] = col.Type
+		}
+	} else {
+		width := 0
+		for _, row := range records {
+			if len(row) > width {
+				width = len(row)
+			}
+		}
+		for i := 1; i <= width; i++ {
+			headers = append(headers, fmt.Sprintf("column%d", i))
+		}
+	}
 
-	for i := 1; i < len(records); i++ {
-		row := records[i]
-		obj := make(map[string]string)
+	results := []interface{}{}
+	for _, row := range records {
+		obj := make(map[string]interface{}, len(headers))
 
 		for j, header := range headers {
-			if j < len(row) {
-				obj[header] = row[j]
+			if j >= len(row) {
+				obj[header] = nil
+				continue
+			}
+			if row[j] == "" && types[j] != "" && types[j] != "string" {
+				obj[header] = nil
+				continue
 			}
+			obj[header] = convertType(row[j], types[j])
+		}
+		if len(row) > len(headers) {
+			obj["_extra"] = row[len(headers):]
 		}
 
 		results = append(results, obj)
 	}
 
-	data, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		return output, err
-	}
-
-	return string(data), nil
-}
-
-// parseXML parses XML output
-func parseXML(output string) (string, error) {
-	// Simple XML to JSON conversion
-	var result interface{}
-	if err := xml.Unmarshal([]byte(output), &result); err != nil {
-		return "", fmt.Errorf("failed to parse XML: %w", err)
-	}
-
-	data, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return output, err
-	}
-
-	return string(data), nil
+	return formatOutput(results, outputCfg)
 }
 
-// convertType converts a string value to the specified type
+// convertType converts a string value to the specified type. The whole
+// value must parse; otherwise the original string is kept, so "1.7" is not
+// an integer and "12 MB" is not a number.
 func convertType(value string, typeName string) interface{} {
 	switch typeName {
 	case "integer":
-		// Try to convert to int
-		var i int
-		if _, err := fmt.Sscanf(value, "%d", &i); err == nil {
+		if i, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
 			return i
 		}
 		return value
 	case "float", "number":
-		// Try to convert to float
-		var f float64
-		if _, err := fmt.Sscanf(value, "%f", &f); err == nil {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
 			return f
 		}
 		return value
@@ -197,7 +362,44 @@ func convertType(value string, typeName string) interface{} {
 			return false
 		}
 		return value
+	case "date":
+		return convertDate(value)
 	default:
 		return value
 	}
+}
+
+// dateLayouts are the date formats recognized by convertDate
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+	time.ANSIC,
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	"02 Jan 2006",
+	"Jan 2, 2006",
+}
+
+// convertDate normalizes a date to YYYY-MM-DD, or a timestamp to RFC 3339.
+// Unrecognized values are returned unchanged.
+func convertDate(value string) interface{} {
+	trimmed := strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, trimmed)
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "15") {
+			return t.Format("2006-01-02")
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+	return value
 }
\ No newline at end of file
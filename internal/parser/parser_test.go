@@ -5,13 +5,21 @@ import (
 	"testing"
 
 	"github.com/charignon/umcp/internal/config"
+	"github.com/charignon/umcp/internal/jq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// mustParseJQ parses a jq filter, as the config loader does
+func mustParseJQ(t *testing.T, text string) *jq.Filter {
+	filter, err := jq.Parse(text)
+	require.NoError(t, err)
+	return filter
+}
+
 func TestParseJSON(t *testing.T) {
 	input := `{"name": "test", "value": 42, "items": ["a", "b", "c"]}`
-	result, err := parseJSON(input, "")
+	result, err := parseJSON(input, nil)
 	require.NoError(t, err)
 
 	// Verify it's valid formatted JSON
@@ -67,7 +75,7 @@ func TestParseRegex(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := parseRegex(tt.input, tt.pattern, tt.groups)
+			result, err := parseRegex(tt.input, &config.Output{Type: "regex", Pattern: tt.pattern, Groups: tt.groups})
 			require.NoError(t, err)
 
 			var matches []map[string]interface{}
@@ -92,7 +100,7 @@ Alice,30,New York
 Bob,25,San Francisco
 Charlie,35,Chicago`
 
-	result, err := parseCSV(input)
+	result, err := parseCSV(input, &config.Output{Type: "csv"})
 	require.NoError(t, err)
 
 	var data []map[string]string
@@ -105,6 +113,75 @@ Charlie,35,Chicago`
 	assert.Equal(t, "New York", data[0]["City"])
 }
 
+func TestParseCSVOptions(t *testing.T) {
+	noHeader := false
+
+	tests := []struct {
+		name     string
+		input    string
+		output   config.Output
+		expected string
+	}{
+		{
+			name:     "tab delimiter with types",
+			input:    "name\tsize\tok\tmodified\na\t10\ttrue\t2024-01-02\nb\t\tfalse\t2024-02-03\n",
+			output:   config.Output{Delimiter: "tab", Columns: []config.Group{{Name: "size", Type: "integer"}, {Name: "ok", Type: "boolean"}, {Name: "modified", Type: "date"}}},
+			expected: `[{"name": "a", "size": 10, "ok": true, "modified": "2024-01-02"}, {"name": "b", "size": null, "ok": false, "modified": "2024-02-03"}]`,
+		},
+		{
+			name:     "pipe delimiter with comments",
+			input:    "# generated\nid|score\n1|2.5\n# trailing\n",
+			output:   config.Output{Delimiter: "|", Comment: "#", Columns: []config.Group{{Name: "id", Type: "integer"}, {Name: "score", Type: "float"}}},
+			expected: `[{"id": 1, "score": 2.5}]`,
+		},
+		{
+			name:     "explicit columns without header",
+			input:    "alice,30\nbob,25\n",
+			output:   config.Output{HasHeader: &noHeader, Columns: []config.Group{{Name: "name"}, {Name: "age", Type: "integer"}}},
+			expected: `[{"name": "alice", "age": 30}, {"name": "bob", "age": 25}]`,
+		},
+		{
+			name:     "generated names without header",
+			input:    "a,b\nc\n",
+			output:   config.Output{HasHeader: &noHeader},
+			expected: `[{"column1": "a", "column2": "b"}, {"column1": "c", "column2": null}]`,
+		},
+		{
+			name:     "ragged rows",
+			input:    "a,b\n1\n1,2,3,4\n",
+			output:   config.Output{},
+			expected: `[{"a": "1", "b": null}, {"a": "1", "b": "2", "_extra": ["3", "4"]}]`,
+		},
+		{
+			name:     "lazy quotes",
+			input:    "name,note\nx,say \"hi\" now\n",
+			output:   config.Output{LazyQuotes: true},
+			expected: `[{"name": "x", "note": "say \"hi\" now"}]`,
+		},
+		{
+			name:     "jq filter",
+			input:    "a,b\n1,2\n3,4\n",
+			output:   config.Output{JQ: ".[].b"},
+			expected: `["2", "4"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.output.Type = "csv"
+			result, err := ParseOutput(tt.input, &tt.output)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+
+	_, err := parseCSV("name,note\nx,say \"hi\" now\n", &config.Output{Type: "csv"})
+	assert.Error(t, err)
+
+	_, err = parseCSV("a,b\n", &config.Output{Type: "csv", Delimiter: ";;"})
+	assert.Error(t, err)
+}
+
 func TestParseOutput(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -211,12 +288,48 @@ func TestConvertType(t *testing.T) {
 			typeName: "boolean",
 			expected: false,
 		},
+		{
+			name:     "date only",
+			value:    "01/02/2024",
+			typeName: "date",
+			expected: "2024-01-02",
+		},
+		{
+			name:     "timestamp",
+			value:    "2024-01-02 03:04:05",
+			typeName: "date",
+			expected: "2024-01-02T03:04:05Z",
+		},
+		{
+			name:     "unrecognized date",
+			value:    "yesterday",
+			typeName: "date",
+			expected: "yesterday",
+		},
 		{
 			name:     "invalid integer keeps string",
 			value:    "not a number",
 			typeName: "integer",
 			expected: "not a number",
 		},
+		{
+			name:     "fractional integer keeps string",
+			value:    "1.7",
+			typeName: "integer",
+			expected: "1.7",
+		},
+		{
+			name:     "integer with unit keeps string",
+			value:    "12 MB",
+			typeName: "integer",
+			expected: "12 MB",
+		},
+		{
+			name:     "float with unit keeps string",
+			value:    "1.5GB",
+			typeName: "float",
+			expected: "1.5GB",
+		},
 	}
 
 	for _, tt := range tests {
@@ -225,4 +338,95 @@ func TestConvertType(t *testing.T) {
 			assert.Equal(t, tt.expected, result)
 		})
 	}
-}
\ No newline at end of file
+}
+func TestParseRegexNamedGroups(t *testing.T) {
+	output := &config.Output{
+		Type:    "regex",
+		Pattern: `(?P<level>\w+): (?P<code>\d+) (.+)`,
+		Groups:  []config.Group{{Name: "code", Type: "integer"}},
+	}
+
+	result, err := parseRegex("error: 404 not found\nwarn: 301 moved\n", output)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"level": "error", "code": 404},
+		{"level": "warn", "code": 301}
+	]`, result)
+
+	output.Single = true
+	result, err = parseRegex("error: 404 not found\nwarn: 301 moved\n", output)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"level": "error", "code": 404}`, result)
+}
+
+func TestParseRegexMultiplePatterns(t *testing.T) {
+	input := `Test run: suite-a
+ok   pkg/one  0.10s
+FAIL pkg/two  0.25s
+ok   pkg/three 0.05s
+Total: 3 packages, 1 failed
+`
+	output := &config.Output{
+		Type: "regex",
+		Patterns: []config.RegexPattern{
+			{Name: "header", Pattern: `Test run: (?P<suite>\S+)`, Single: true},
+			{Name: "results", Pattern: `(?m)^(ok|FAIL)\s+(\S+)\s+([\d.]+)s$`, Groups: []config.Group{
+				{Name: "status"}, {Name: "package"}, {Name: "seconds", Type: "float"},
+			}},
+			{Name: "summary", Pattern: `Total: (?P<total>\d+) packages, (?P<failed>\d+) failed`, Single: true, Groups: []config.Group{
+				{Name: "total", Type: "integer"}, {Name: "failed", Type: "integer"},
+			}},
+			{Name: "missing", Pattern: `panic: (.+)`, Single: true},
+		},
+	}
+
+	result, err := parseRegex(input, output)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"header": {"suite": "suite-a"},
+		"results": [
+			{"status": "ok", "package": "pkg/one", "seconds": 0.1},
+			{"status": "FAIL", "package": "pkg/two", "seconds": 0.25},
+			{"status": "ok", "package": "pkg/three", "seconds": 0.05}
+		],
+		"summary": {"total": 3, "failed": 1},
+		"missing": null
+	}`, result)
+}
+
+func TestParseRegexRecordSeparator(t *testing.T) {
+	input := `commit 1a2b3c
+Author: Alice <alice@example.com>
+Date:   Mon Jan 1 10:00:00 2024
+
+    Fix parser
+
+commit 4d5e6f
+Author: Bob <bob@example.com>
+Date:   Sun Dec 31 09:00:00 2023
+
+    Initial commit
+`
+	output := &config.Output{
+		Type:            "regex",
+		RecordSeparator: `(?m)^commit `,
+		Pattern:         `^commit (?P<hash>\w+)`,
+		Patterns: []config.RegexPattern{
+			{Pattern: `Author: (?P<author>[^<]+?) <(?P<email>[^>]+)>`},
+			{Pattern: `(?m)^ {4}(?P<subject>.+)$`},
+		},
+		JQ: ".[] | select(.author == \"Bob\") | .subject",
+	}
+
+	result, err := parseRegex(input, output)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["Initial commit"]`, result)
+
+	output.JQ = ""
+	result, err = parseRegex(input, output)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"hash": "1a2b3c", "author": "Alice", "email": "alice@example.com", "subject": "Fix parser"},
+		{"hash": "4d5e6f", "author": "Bob", "email": "bob@example.com", "subject": "Initial commit"}
+	]`, result)
+}
@@ -0,0 +1,202 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/charignon/umcp/internal/jq"
+)
+
+// tableColumn is a column of a whitespace-aligned table. end is exclusive;
+// -1 means the column extends to the end of the line.
+type tableColumn struct {
+	name       string
+	typeName   string
+	start, end int
+}
+
+// parseTable parses a whitespace-aligned table with a header row into an
+// array of objects. Column boundaries come from columns with explicit
+// positions, or are inferred from the runs of whitespace shared by every
+// line. Columns without positions act as type hints for inferred columns.
+func parseTable(output string, columns []config.Group, filter *jq.Filter) (string, error) {
+	var lines [][]rune
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(expandTabs(line), " \r")
+		if line != "" {
+			lines = append(lines, []rune(line))
+		}
+	}
+	if len(lines) == 0 {
+		return formatJSON([]interface{}{}, filter)
+	}
+
+	var cols []tableColumn
+	if hasColumnPositions(columns) {
+		for _, c := range columns {
+			end := c.End
+			if end == 0 {
+				end = -1
+			}
+			cols = append(cols, tableColumn{name: c.Name, typeName: c.Type, start: c.Start, end: end})
+		}
+	} else {
+		cols = inferTableColumns(lines)
+		if len(cols) == 0 {
+			return "", fmt.Errorf("failed to parse table: no columns found in header")
+		}
+		for i := range cols {
+			for _, hint := range columns {
+				if strings.EqualFold(hint.Name, cols[i].name) {
+					cols[i].typeName = hint.Type
+				}
+			}
+		}
+	}
+
+	records := []interface{}{}
+	for _, line := range lines[1:] {
+		record := make(map[string]interface{}, len(cols))
+		for _, col := range cols {
+			cell := strings.TrimSpace(sliceRunes(line, col.start, col.end))
+			if cell == "" && col.typeName != "" && col.typeName != "string" {
+				record[col.name] = nil
+				continue
+			}
+			record[col.name] = convertType(cell, col.typeName)
+		}
+		records = append(records, record)
+	}
+
+	return formatJSON(records, filter)
+}
+
+// inferTableColumns finds column boundaries from a mask of character
+// positions that are blank on every line. Each run of non-blank positions
+// is a segment; segments without header text belong to the column on
+// their left, and header-only segments separated by a single space are
+// the words of a multi-word header.
+func inferTableColumns(lines [][]rune) []tableColumn {
+	width := 0
+	for _, line := range lines {
+		if len(line) > width {
+			width = len(line)
+		}
+	}
+
+	blank := make([]bool, width)
+	for i := range blank {
+		blank[i] = true
+		for _, line := range lines {
+			if i < len(line) && line[i] != ' ' {
+				blank[i] = false
+				break
+			}
+		}
+	}
+
+	type segment struct{ start, end int }
+	var segments []segment
+	for i := 0; i < width; {
+		if blank[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < width && !blank[i] {
+			i++
+		}
+		segments = append(segments, segment{start, i})
+	}
+
+	header := lines[0]
+	var cols []tableColumn
+	pending := -1
+	for _, seg := range segments {
+		name := strings.TrimSpace(sliceRunes(header, seg.start, seg.end))
+		if len(cols) == 0 && name == "" {
+			// Data to the left of the first header word joins the first column
+			if pending < 0 {
+				pending = seg.start
+			}
+			continue
+		}
+		if len(cols) > 0 {
+			prev := &cols[len(cols)-1]
+			merge := name == ""
+			if !merge && seg.start-prev.end == 1 && columnIsEmpty(lines[1:], seg.start, seg.end) {
+				merge = true
+			}
+			if merge {
+				if name != "" {
+					prev.name += " " + name
+				}
+				prev.end = seg.end
+				continue
+			}
+		}
+		start := seg.start
+		if pending >= 0 {
+			start, pending = pending, -1
+		}
+		cols = append(cols, tableColumn{name: name, start: start, end: seg.end})
+	}
+
+	if len(cols) > 0 {
+		cols[len(cols)-1].end = -1
+	}
+	return cols
+}
+
+// columnIsEmpty reports whether every line is blank between start and end
+func columnIsEmpty(lines [][]rune, start, end int) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(sliceRunes(line, start, end)) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasColumnPositions reports whether any column spec sets explicit positions
+func hasColumnPositions(columns []config.Group) bool {
+	for _, c := range columns {
+		if c.Start > 0 || c.End > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sliceRunes returns line[start:end] clamped to the line; end -1 means the
+// end of the line
+func sliceRunes(line []rune, start, end int) string {
+	if end < 0 || end > len(line) {
+		end = len(line)
+	}
+	if start >= end {
+		return ""
+	}
+	return string(line[start:end])
+}
+
+// expandTabs replaces tabs with spaces up to the next multiple of 8
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var sb strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := 8 - col%8
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		sb.WriteRune(r)
+		col++
+	}
+	return sb.String()
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTableInferred(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "multi-word headers and empty cells",
+			input: `CONTAINER ID   IMAGE          COMMAND       CREATED        STATUS         PORTS     NAMES
+4c01db0b339c   nginx:latest   "nginx -g…"   2 hours ago    Up 2 hours     80/tcp    web
+d7886598dbe2   redis:7        "redis"       3 days ago     Exited (0)               cache
+`,
+			expected: `[
+				{"CONTAINER ID": "4c01db0b339c", "IMAGE": "nginx:latest", "COMMAND": "\"nginx -g…\"", "CREATED": "2 hours ago", "STATUS": "Up 2 hours", "PORTS": "80/tcp", "NAMES": "web"},
+				{"CONTAINER ID": "d7886598dbe2", "IMAGE": "redis:7", "COMMAND": "\"redis\"", "CREATED": "3 days ago", "STATUS": "Exited (0)", "PORTS": "", "NAMES": "cache"}
+			]`,
+		},
+		{
+			name: "header words over short values",
+			input: `LAST SEEN   TYPE      REASON
+5m          Normal    Pulled
+12m         Warning   BackOff
+`,
+			expected: `[
+				{"LAST SEEN": "5m", "TYPE": "Normal", "REASON": "Pulled"},
+				{"LAST SEEN": "12m", "TYPE": "Warning", "REASON": "BackOff"}
+			]`,
+		},
+		{
+			name: "trailing text joins last column",
+			input: `USER   PID  COMMAND
+root     1  /sbin/init splash
+me     420  vim notes.txt
+`,
+			expected: `[
+				{"USER": "root", "PID": "1", "COMMAND": "/sbin/init splash"},
+				{"USER": "me", "PID": "420", "COMMAND": "vim notes.txt"}
+			]`,
+		},
+		{
+			name:     "header only",
+			input:    "NAME   READY\n",
+			expected: `[]`,
+		},
+		{
+			name:     "empty",
+			input:    "\n\n",
+			expected: `[]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseTable(tt.input, nil, nil)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParseTableTypeHints(t *testing.T) {
+	input := `NAME     SIZE   ACTIVE
+alpha    10     true
+beta            false
+`
+	columns := []config.Group{
+		{Name: "size", Type: "integer"},
+		{Name: "ACTIVE", Type: "boolean"},
+	}
+
+	result, err := parseTable(input, columns, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"NAME": "alpha", "SIZE": 10, "ACTIVE": true},
+		{"NAME": "beta", "SIZE": null, "ACTIVE": false}
+	]`, result)
+
+	result, err = parseTable(input, columns, mustParseJQ(t, `.[] | select(.ACTIVE == true) | .NAME`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `["alpha"]`, result)
+}
+
+func TestParseTableExplicitColumns(t *testing.T) {
+	input := `MODE      SIZE NAME
+drwxr-xr-x  64 my dir
+-rw-r--r--1024 file.txt
+`
+	columns := []config.Group{
+		{Name: "mode", Start: 0, End: 10},
+		{Name: "size", Type: "integer", Start: 10, End: 14},
+		{Name: "name", Start: 15},
+	}
+
+	result, err := parseTable(input, columns, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"mode": "drwxr-xr-x", "size": 64, "name": "my dir"},
+		{"mode": "-rw-r--r--", "size": 1024, "name": "file.txt"}
+	]`, result)
+}
+
+func TestParseTableTabs(t *testing.T) {
+	input := "NAME\tSTATUS\nweb\tok\n"
+	result, err := ParseOutput(input, &config.Output{Type: "table"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"NAME": "web", "STATUS": "ok"}]`, result)
+}
@@ -0,0 +1,501 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charignon/umcp/internal/jq"
+)
+
+// parseTOML parses TOML output. It supports tables, arrays of tables,
+// dotted and quoted keys, strings (basic, literal and multi-line),
+// integers, floats, booleans, arrays and inline tables. Dates and times
+// are kept as strings.
+func parseTOML(output string, filter *jq.Filter) (string, error) {
+	data, err := decodeTOML(output)
+	if err != nil {
+		return "", fmt.Errorf("invalid TOML: %w", err)
+	}
+	return formatJSON(data, filter)
+}
+
+// tomlDecoder is a cursor over TOML input
+type tomlDecoder struct {
+	input string
+	pos   int
+	line  int
+}
+
+// decodeTOML decodes a TOML document into maps and slices
+func decodeTOML(input string) (map[string]interface{}, error) {
+	d := &tomlDecoder{input: input, line: 1}
+	root := make(map[string]interface{})
+	current := root
+
+	for {
+		d.skipWhitespaceAndComments(true)
+		if d.eof() {
+			return root, nil
+		}
+
+		var err error
+		switch {
+		case strings.HasPrefix(d.input[d.pos:], "[["):
+			d.pos += 2
+			current, err = d.parseTableHeader(root, "]]", true)
+		case d.peek() == '[':
+			d.pos++
+			current, err = d.parseTableHeader(root, "]", false)
+		default:
+			err = d.parseKeyValue(current)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", d.line, err)
+		}
+
+		d.skipWhitespaceAndComments(false)
+		if !d.eof() && d.peek() != '\n' && d.peek() != '\r' {
+			return nil, fmt.Errorf("line %d: unexpected %q after value", d.line, d.peek())
+		}
+	}
+}
+
+// parseTableHeader parses [a.b] or [[a.b]] and returns the table to fill
+func (d *tomlDecoder) parseTableHeader(root map[string]interface{}, closing string, array bool) (map[string]interface{}, error) {
+	keys, err := d.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	d.skipSpaces()
+	if !strings.HasPrefix(d.input[d.pos:], closing) {
+		return nil, fmt.Errorf("expected %s after table name", closing)
+	}
+	d.pos += len(closing)
+
+	parent, err := descend(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+
+	if array {
+		table := make(map[string]interface{})
+		switch existing := parent[last].(type) {
+		case nil:
+			parent[last] = []interface{}{table}
+		case []interface{}:
+			parent[last] = append(existing, table)
+		default:
+			return nil, fmt.Errorf("key %s is already defined", last)
+		}
+		return table, nil
+	}
+
+	switch existing := parent[last].(type) {
+	case nil:
+		table := make(map[string]interface{})
+		parent[last] = table
+		return table, nil
+	case map[string]interface{}:
+		return existing, nil
+	default:
+		return nil, fmt.Errorf("key %s is already defined", last)
+	}
+}
+
+// parseKeyValue parses key = value into table
+func (d *tomlDecoder) parseKeyValue(table map[string]interface{}) error {
+	keys, err := d.parseKey()
+	if err != nil {
+		return err
+	}
+	d.skipSpaces()
+	if d.peek() != '=' {
+		return fmt.Errorf("expected = after key %s", strings.Join(keys, "."))
+	}
+	d.pos++
+	d.skipSpaces()
+
+	value, err := d.parseValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := parent[last]; exists {
+		return fmt.Errorf("key %s is already defined", strings.Join(keys, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// descend walks (and creates) nested tables along keys. For arrays of
+// tables it follows the most recent element.
+func descend(table map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, key := range keys {
+		switch next := table[key].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			table[key] = child
+			table = child
+		case map[string]interface{}:
+			table = next
+		case []interface{}:
+			last, ok := next[len(next)-1].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("key %s is not a table", key)
+			}
+			table = last
+		default:
+			return nil, fmt.Errorf("key %s is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+// parseKey parses a possibly dotted key made of bare or quoted parts
+func (d *tomlDecoder) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		d.skipSpaces()
+		var key string
+		switch d.peek() {
+		case '"':
+			s, err := d.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case '\'':
+			s, err := d.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := d.pos
+			for !d.eof() && isBareKeyChar(d.peek()) {
+				d.pos++
+			}
+			if start == d.pos {
+				return nil, fmt.Errorf("expected key")
+			}
+			key = d.input[start:d.pos]
+		}
+		keys = append(keys, key)
+
+		d.skipSpaces()
+		if d.peek() != '.' {
+			return keys, nil
+		}
+		d.pos++
+	}
+}
+
+// parseValue parses any TOML value
+func (d *tomlDecoder) parseValue() (interface{}, error) {
+	rest := d.input[d.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return d.parseMultilineString(`"""`, true)
+	case strings.HasPrefix(rest, "'''"):
+		return d.parseMultilineString("'''", false)
+	case strings.HasPrefix(rest, `"`):
+		return d.parseBasicString()
+	case strings.HasPrefix(rest, "'"):
+		return d.parseLiteralString()
+	case strings.HasPrefix(rest, "["):
+		return d.parseArray()
+	case strings.HasPrefix(rest, "{"):
+		return d.parseInlineTable()
+	}
+
+	start := d.pos
+	for !d.eof() && !strings.ContainsRune(",]}#\r\n", rune(d.peek())) {
+		d.pos++
+	}
+	token := strings.TrimSpace(d.input[start:d.pos])
+	d.pos = start + len(token)
+	if token == "" {
+		return nil, fmt.Errorf("expected value")
+	}
+	return parseTOMLScalar(token)
+}
+
+// parseTOMLScalar converts a bare token to a boolean, number or date string
+func parseTOMLScalar(token string) (interface{}, error) {
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf", "-inf", "nan", "+nan", "-nan":
+		// JSON has no representation for these, so keep them as strings
+		return token, nil
+	}
+
+	clean := strings.ReplaceAll(token, "_", "")
+	for prefix, base := range map[string]int{"0x": 16, "0o": 8, "0b": 2} {
+		if strings.HasPrefix(clean, prefix) {
+			n, err := strconv.ParseInt(clean[2:], base, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer %q", token)
+			}
+			return n, nil
+		}
+	}
+	if n, err := strconv.ParseInt(clean, 10, 64); err == nil {
+		return n, nil
+	}
+	if isTOMLDateTime(token) {
+		return token, nil
+	}
+	if f, err := strconv.ParseFloat(clean, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("invalid value %q", token)
+}
+
+// isTOMLDateTime reports whether token looks like a date, time or datetime
+func isTOMLDateTime(token string) bool {
+	if len(token) >= 10 && token[4] == '-' && token[7] == '-' {
+		return true
+	}
+	return len(token) >= 8 && token[2] == ':' && token[5] == ':'
+}
+
+// parseArray parses [a, b, ...], allowing newlines, comments and a
+// trailing comma
+func (d *tomlDecoder) parseArray() ([]interface{}, error) {
+	d.pos++ // skip '['
+	values := []interface{}{}
+	for {
+		d.skipWhitespaceAndComments(true)
+		if d.peek() == ']' {
+			d.pos++
+			return values, nil
+		}
+		value, err := d.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		d.skipWhitespaceAndComments(true)
+		switch d.peek() {
+		case ',':
+			d.pos++
+		case ']':
+			d.pos++
+			return values, nil
+		default:
+			return nil, fmt.Errorf("expected , or ] in array")
+		}
+	}
+}
+
+// parseInlineTable parses { a = 1, b = "x" }
+func (d *tomlDecoder) parseInlineTable() (map[string]interface{}, error) {
+	d.pos++ // skip '{'
+	table := make(map[string]interface{})
+	d.skipSpaces()
+	if d.peek() == '}' {
+		d.pos++
+		return table, nil
+	}
+	for {
+		if err := d.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		d.skipSpaces()
+		switch d.peek() {
+		case ',':
+			d.pos++
+		case '}':
+			d.pos++
+			return table, nil
+		default:
+			return nil, fmt.Errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// parseBasicString parses "..." with escapes
+func (d *tomlDecoder) parseBasicString() (string, error) {
+	d.pos++ // skip opening quote
+	var sb strings.Builder
+	for !d.eof() {
+		c := d.peek()
+		switch c {
+		case '"':
+			d.pos++
+			return sb.String(), nil
+		case '\n':
+			return "", fmt.Errorf("newline in string")
+		case '\\':
+			if err := d.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteByte(c)
+			d.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// parseLiteralString parses '...' without escapes
+func (d *tomlDecoder) parseLiteralString() (string, error) {
+	d.pos++ // skip opening quote
+	end := strings.IndexAny(d.input[d.pos:], "'\n")
+	if end < 0 || d.input[d.pos+end] != '\'' {
+		return "", fmt.Errorf("unterminated string")
+	}
+	s := d.input[d.pos : d.pos+end]
+	d.pos += end + 1
+	return s, nil
+}
+
+// parseMultilineString parses """...""" or '''...'''. A newline directly
+// after the opening delimiter is trimmed.
+func (d *tomlDecoder) parseMultilineString(delim string, escapes bool) (string, error) {
+	d.pos += len(delim)
+	if strings.HasPrefix(d.input[d.pos:], "\r\n") {
+		d.pos += 2
+		d.line++
+	} else if d.peek() == '\n' {
+		d.pos++
+		d.line++
+	}
+
+	var sb strings.Builder
+	for !d.eof() {
+		if strings.HasPrefix(d.input[d.pos:], delim) {
+			d.pos += len(delim)
+			// Up to two quotes may directly precede the closing delimiter
+			for i := 0; i < 2 && strings.HasPrefix(d.input[d.pos:], delim[:1]); i++ {
+				sb.WriteByte(delim[0])
+				d.pos++
+			}
+			return sb.String(), nil
+		}
+		c := d.peek()
+		if escapes && c == '\\' {
+			// A backslash at the end of a line trims the following whitespace
+			rest := strings.TrimLeft(d.input[d.pos+1:], " \t")
+			if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				d.pos++
+				for !d.eof() && strings.ContainsRune(" \t\r\n", rune(d.peek())) {
+					if d.peek() == '\n' {
+						d.line++
+					}
+					d.pos++
+				}
+				continue
+			}
+			if err := d.parseEscape(&sb); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if c == '\n' {
+			d.line++
+		}
+		sb.WriteByte(c)
+		d.pos++
+	}
+	return "", fmt.Errorf("unterminated multi-line string")
+}
+
+// parseEscape decodes one backslash escape sequence
+func (d *tomlDecoder) parseEscape(sb *strings.Builder) error {
+	d.pos++ // skip backslash
+	if d.eof() {
+		return fmt.Errorf("unterminated escape")
+	}
+	c := d.peek()
+	d.pos++
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case '"':
+		sb.WriteByte('"')
+	case '\\':
+		sb.WriteByte('\\')
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if d.pos+size > len(d.input) {
+			return fmt.Errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(d.input[d.pos:d.pos+size], 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid unicode escape")
+		}
+		sb.WriteRune(rune(code))
+		d.pos += size
+	default:
+		return fmt.Errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+// skipSpaces skips spaces and tabs
+func (d *tomlDecoder) skipSpaces() {
+	for !d.eof() && (d.peek() == ' ' || d.peek() == '\t') {
+		d.pos++
+	}
+}
+
+// skipWhitespaceAndComments skips spaces, tabs and comments, and also
+// newlines when newlines is true
+func (d *tomlDecoder) skipWhitespaceAndComments(newlines bool) {
+	for !d.eof() {
+		switch c := d.peek(); {
+		case c == ' ' || c == '\t':
+			d.pos++
+		case newlines && (c == '\n' || c == '\r'):
+			if c == '\n' {
+				d.line++
+			}
+			d.pos++
+		case c == '#':
+			for !d.eof() && d.peek() != '\n' {
+				d.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (d *tomlDecoder) eof() bool {
+	return d.pos >= len(d.input)
+}
+
+func (d *tomlDecoder) peek() byte {
+	if d.eof() {
+		return 0
+	}
+	return d.input[d.pos]
+}
+
+// isBareKeyChar reports whether c may appear in a bare TOML key
+func isBareKeyChar(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTOML(t *testing.T) {
+	input := `# Cargo manifest
+title = "example"
+"quoted key" = 'C:\path'
+version.major = 1
+hex = 0xff
+big = 1_000_000
+ratio = -3.5
+enabled = true
+released = 1979-05-27T07:32:00Z
+ports = [
+  80,
+  443, # https
+]
+owner = { name = "Tom", admin = false }
+notes = """
+line one
+line two"""
+
+[package]
+name = "umcp"
+authors = ["a", "b"]
+
+[package.metadata]
+docs = 'https://example.com'
+
+[[bin]]
+name = "first"
+
+[[bin]]
+name = "second"
+path = "src/second.rs"
+`
+
+	result, err := parseTOML(input, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"title": "example",
+		"quoted key": "C:\\path",
+		"version": {"major": 1},
+		"hex": 255,
+		"big": 1000000,
+		"ratio": -3.5,
+		"enabled": true,
+		"released": "1979-05-27T07:32:00Z",
+		"ports": [80, 443],
+		"owner": {"name": "Tom", "admin": false},
+		"notes": "line one\nline two",
+		"package": {
+			"name": "umcp",
+			"authors": ["a", "b"],
+			"metadata": {"docs": "https://example.com"}
+		},
+		"bin": [
+			{"name": "first"},
+			{"name": "second", "path": "src/second.rs"}
+		]
+	}`, result)
+
+	result, err = parseTOML(input, mustParseJQ(t, ".bin[].name"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `["first", "second"]`, result)
+}
+
+func TestParseTOMLStrings(t *testing.T) {
+	input := `escaped = "tab\there \"quoted\" \u00e9"
+literal = '''raw \n text'''
+trimmed = """\
+    joined \
+    words"""
+`
+	result, err := parseTOML(input, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"escaped": "tab\there \"quoted\" é",
+		"literal": "raw \\n text",
+		"trimmed": "joined words"
+	}`, result)
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing equals", input: "key value\n"},
+		{name: "duplicate key", input: "a = 1\na = 2\n"},
+		{name: "unterminated string", input: "a = \"oops\n"},
+		{name: "unterminated array", input: "a = [1, 2\n"},
+		{name: "invalid value", input: "a = nope\n"},
+		{name: "trailing garbage", input: "a = 1 2\n"},
+		{name: "table redefines value", input: "a = 1\n[a]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOML(tt.input, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid TOML")
+		})
+	}
+}
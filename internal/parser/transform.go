@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charignon/umcp/internal/config"
+)
+
+// BytesPerToken is the rough number of bytes per model token used to
+// estimate token counts
+const BytesPerToken = 4
+
+// ansiPattern matches ANSI CSI and OSC escape sequences
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// ApplyTransforms runs the line-oriented transform steps on raw output.
+// max_tokens steps are skipped here; see TruncateTokens.
+func ApplyTransforms(output string, steps []config.TransformStep) (string, error) {
+	if len(steps) == 0 {
+		return output, nil
+	}
+
+	trailingNewline := strings.HasSuffix(output, "\n")
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if output == "" {
+		lines = nil
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		switch {
+		case step.StripANSI:
+			for j, line := range lines {
+				lines[j] = ansiPattern.ReplaceAllString(line, "")
+			}
+		case step.Head > 0:
+			if len(lines) > step.Head {
+				lines = lines[:step.Head]
+			}
+		case step.Tail > 0:
+			if len(lines) > step.Tail {
+				lines = lines[len(lines)-step.Tail:]
+			}
+		case step.Include != "" || step.Exclude != "":
+			re, err := step.Regexp()
+			if err != nil {
+				return "", fmt.Errorf("transform step %d: %w", i+1, err)
+			}
+			keep := step.Include != ""
+			filtered := lines[:0]
+			for _, line := range lines {
+				if re.MatchString(line) == keep {
+					filtered = append(filtered, line)
+				}
+			}
+			lines = filtered
+		case step.Dedupe:
+			seen := make(map[string]bool, len(lines))
+			filtered := lines[:0]
+			for _, line := range lines {
+				if !seen[line] {
+					seen[line] = true
+					filtered = append(filtered, line)
+				}
+			}
+			lines = filtered
+		}
+	}
+
+	result := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// OutputLimit returns the size in bytes the final output is truncated to:
+// the smaller of maxOutputSize and the token budget of a max_tokens step.
+// Zero means unlimited.
+func OutputLimit(steps []config.TransformStep, maxOutputSize int64) int {
+	limit := int(maxOutputSize)
+	if tokens := MaxTokens(steps); tokens > 0 && (limit <= 0 || tokens*BytesPerToken < limit) {
+		limit = tokens * BytesPerToken
+	}
+	return limit
+}
+
+// MaxTokens returns the token budget set by a max_tokens step, or 0
+func MaxTokens(steps []config.TransformStep) int {
+	for _, step := range steps {
+		if step.MaxTokens > 0 {
+			return step.MaxTokens
+		}
+	}
+	return 0
+}
+
+// Truncate splits output so the first part is at most maxBytes long. It
+// never cuts inside a UTF-8 sequence and prefers to cut after a newline in
+// the second half of the allowed size.
+func Truncate(output string, maxBytes int) (string, string) {
+	if maxBytes <= 0 || len(output) <= maxBytes {
+		return output, ""
+	}
+
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		// Always make progress, even if the first rune is larger than maxBytes
+		_, cut = utf8.DecodeRuneInString(output)
+	}
+	if nl := strings.LastIndexByte(output[:cut], '\n'); nl >= cut/2 {
+		cut = nl + 1
+	}
+	return output[:cut], output[cut:]
+}
@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyTransforms(t *testing.T) {
+	input := "\x1b[32mok\x1b[0m line1\nerror: a\nok line2\nerror: a\nwarning: b\n"
+
+	tests := []struct {
+		name     string
+		steps    []config.TransformStep
+		expected string
+	}{
+		{
+			name:     "no steps",
+			steps:    nil,
+			expected: input,
+		},
+		{
+			name:     "strip ansi",
+			steps:    []config.TransformStep{{StripANSI: true}, {Head: 1}},
+			expected: "ok line1\n",
+		},
+		{
+			name:     "head",
+			steps:    []config.TransformStep{{Head: 2}},
+			expected: "\x1b[32mok\x1b[0m line1\nerror: a\n",
+		},
+		{
+			name:     "tail",
+			steps:    []config.TransformStep{{Tail: 2}},
+			expected: "error: a\nwarning: b\n",
+		},
+		{
+			name:     "include",
+			steps:    []config.TransformStep{{Include: `^error`}},
+			expected: "error: a\nerror: a\n",
+		},
+		{
+			name:     "exclude then dedupe",
+			steps:    []config.TransformStep{{Exclude: `ok`}, {Dedupe: true}},
+			expected: "error: a\nwarning: b\n",
+		},
+		{
+			name:     "max tokens is not a line step",
+			steps:    []config.TransformStep{{MaxTokens: 1}, {Tail: 1}},
+			expected: "warning: b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ApplyTransforms(input, tt.steps)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		maxBytes int
+		head     string
+		rest     string
+	}{
+		{
+			name:     "fits",
+			input:    "short",
+			maxBytes: 10,
+			head:     "short",
+		},
+		{
+			name:     "unlimited",
+			input:    "short",
+			maxBytes: 0,
+			head:     "short",
+		},
+		{
+			name:     "cuts after newline",
+			input:    "line one\nline two\n",
+			maxBytes: 12,
+			head:     "line one\n",
+			rest:     "line two\n",
+		},
+		{
+			name:     "never splits a rune",
+			input:    "héllo",
+			maxBytes: 2,
+			head:     "h",
+			rest:     "éllo",
+		},
+		{
+			name:     "makes progress on a wide rune",
+			input:    "€uro",
+			maxBytes: 1,
+			head:     "€",
+			rest:     "uro",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, rest := Truncate(tt.input, tt.maxBytes)
+			assert.Equal(t, tt.head, head)
+			assert.Equal(t, tt.rest, rest)
+		})
+	}
+}
+
+func TestOutputLimit(t *testing.T) {
+	assert.Equal(t, 1000, OutputLimit(nil, 1000))
+	assert.Equal(t, 400, OutputLimit([]config.TransformStep{{MaxTokens: 100}}, 1000))
+	assert.Equal(t, 1000, OutputLimit([]config.TransformStep{{MaxTokens: 1000}}, 1000))
+	assert.Equal(t, 400, OutputLimit([]config.TransformStep{{MaxTokens: 100}}, 0))
+	assert.Equal(t, 0, OutputLimit(nil, 0))
+
+	long := strings.Repeat("x", 10)
+	head, rest := Truncate(long, OutputLimit([]config.TransformStep{{MaxTokens: 1}}, 0))
+	assert.Equal(t, "xxxx", head)
+	assert.Equal(t, "xxxxxx", rest)
+}
@@ -0,0 +1,411 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charignon/umcp/internal/jq"
+)
+
+// xmlNamespace is the namespace URL bound to the reserved xml prefix
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// xmlNode is an element of a decoded XML document
+type xmlNode struct {
+	name     string
+	attrs    []xmlAttr
+	children []*xmlNode
+	text     strings.Builder
+}
+
+type xmlAttr struct {
+	name  string
+	value string
+}
+
+// parseXML converts XML output to JSON. Attributes become "@name" keys,
+// text becomes "#text" (or the element's value when it has no attributes
+// or children), and repeated child elements become arrays. Namespaced
+// names use the prefix declared in the document. An optional XPath-like
+// selector returns the matching nodes as an array.
+func parseXML(output string, selector string, filter *jq.Filter) (string, error) {
+	root, err := decodeXML(output)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse XML: %w", err)
+	}
+
+	if selector == "" {
+		return formatJSON(map[string]interface{}{root.name: root.toJSON()}, filter)
+	}
+
+	matches, err := selectXML(root, selector)
+	if err != nil {
+		return "", err
+	}
+	values := make([]interface{}, 0, len(matches))
+	for _, match := range matches {
+		if node, ok := match.(*xmlNode); ok {
+			values = append(values, node.toJSON())
+		} else {
+			values = append(values, match)
+		}
+	}
+	return formatJSON(values, filter)
+}
+
+// decodeXML builds the element tree of a document
+func decodeXML(output string) (*xmlNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(output))
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = charsetReader
+
+	prefixes := map[string]string{xmlNamespace: "xml"}
+	var stack []*xmlNode
+	var root *xmlNode
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{}
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					if _, ok := prefixes[attr.Value]; !ok {
+						prefixes[attr.Value] = attr.Name.Local
+					}
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					// Default namespace: elements keep their local names
+				default:
+					node.attrs = append(node.attrs, xmlAttr{name: xmlName(attr.Name, prefixes), value: attr.Value})
+				}
+			}
+			node.name = xmlName(t.Name, prefixes)
+
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return root, nil
+}
+
+// xmlName renders a name as prefix:local, or local for names in the
+// default namespace
+func xmlName(name xml.Name, prefixes map[string]string) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	if prefix, ok := prefixes[name.Space]; ok {
+		return prefix + ":" + name.Local
+	}
+	if !strings.Contains(name.Space, "/") && !strings.Contains(name.Space, ":") {
+		// An undeclared prefix is reported as-is
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// toJSON converts a node to the JSON value of its content
+func (n *xmlNode) toJSON() interface{} {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.attrs) == 0 && len(n.children) == 0 {
+		return text
+	}
+
+	obj := make(map[string]interface{})
+	for _, attr := range n.attrs {
+		obj["@"+attr.name] = attr.value
+	}
+	for _, child := range n.children {
+		value := child.toJSON()
+		switch existing := obj[child.name].(type) {
+		case nil:
+			obj[child.name] = value
+		case []interface{}:
+			obj[child.name] = append(existing, value)
+		default:
+			obj[child.name] = []interface{}{existing, value}
+		}
+	}
+	if text != "" {
+		obj["#text"] = text
+	}
+	return obj
+}
+
+// descendants returns the node and all elements below it in document order
+func (n *xmlNode) descendants() []*xmlNode {
+	result := []*xmlNode{n}
+	for _, child := range n.children {
+		result = append(result, child.descendants()...)
+	}
+	return result
+}
+
+// attr returns the value of an attribute
+func (n *xmlNode) attr(name string) (string, bool) {
+	for _, attr := range n.attrs {
+		if attr.name == name {
+			return attr.value, true
+		}
+	}
+	return "", false
+}
+
+// xmlStep is one location step of a selector
+type xmlStep struct {
+	descendant bool   // preceded by //
+	name       string // element name, *, @attr or text()
+	predicates []string
+}
+
+// selectXML evaluates an XPath-like selector. Supported syntax:
+//
+//	/a/b      child steps from the document root (a leading / is optional)
+//	//b       descendants at any depth
+//	*         any element
+//	@id       attribute value
+//	text()    element text
+//	b[2]      1-based position
+//	b[@id]    elements with an attribute
+//	b[@id='x'] and b[name='x']  attribute or child text equality
+//
+// Matches are returned as nodes, or as strings for @attr and text().
+func selectXML(root *xmlNode, selector string) ([]interface{}, error) {
+	steps, err := parseXMLSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XML selector %q: %w", selector, err)
+	}
+
+	// The document node is the parent of the root element
+	document := &xmlNode{children: []*xmlNode{root}}
+	context := []*xmlNode{document}
+
+	for i, step := range steps {
+		var candidates []*xmlNode
+		for _, node := range context {
+			if step.descendant {
+				candidates = append(candidates, node.descendants()...)
+			} else {
+				candidates = append(candidates, node)
+			}
+		}
+
+		if strings.HasPrefix(step.name, "@") || step.name == "text()" {
+			if i != len(steps)-1 {
+				return nil, fmt.Errorf("invalid XML selector %q: %s must be the last step", selector, step.name)
+			}
+			var values []interface{}
+			for _, node := range dedupeNodes(candidates) {
+				if step.name == "text()" {
+					if text := strings.TrimSpace(node.text.String()); text != "" {
+						values = append(values, text)
+					}
+				} else if value, ok := node.attr(step.name[1:]); ok {
+					values = append(values, value)
+				}
+			}
+			return values, nil
+		}
+
+		var next []*xmlNode
+		for _, node := range dedupeNodes(candidates) {
+			var matched []*xmlNode
+			for _, child := range node.children {
+				if step.name == "*" || child.name == step.name {
+					matched = append(matched, child)
+				}
+			}
+			for _, predicate := range step.predicates {
+				matched, err = filterXMLNodes(matched, predicate)
+				if err != nil {
+					return nil, fmt.Errorf("invalid XML selector %q: %w", selector, err)
+				}
+			}
+			next = append(next, matched...)
+		}
+		context = next
+	}
+
+	values := make([]interface{}, 0, len(context))
+	for _, node := range context {
+		values = append(values, node)
+	}
+	return values, nil
+}
+
+// parseXMLSelector splits a selector into location steps
+func parseXMLSelector(selector string) ([]xmlStep, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+
+	var steps []xmlStep
+	i := 0
+	for i < len(selector) {
+		step := xmlStep{}
+		if strings.HasPrefix(selector[i:], "//") {
+			step.descendant = true
+			i += 2
+		} else if selector[i] == '/' {
+			i++
+		}
+
+		start := i
+		for i < len(selector) && selector[i] != '/' && selector[i] != '[' {
+			i++
+		}
+		step.name = strings.TrimSpace(selector[start:i])
+		if step.name == "" {
+			return nil, fmt.Errorf("empty step at offset %d", start)
+		}
+
+		for i < len(selector) && selector[i] == '[' {
+			end := strings.IndexByte(selector[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+			step.predicates = append(step.predicates, strings.TrimSpace(selector[i+1:i+end]))
+			i += end + 1
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// filterXMLNodes applies a predicate to the nodes matched by one step
+func filterXMLNodes(nodes []*xmlNode, predicate string) ([]*xmlNode, error) {
+	if n, err := strconv.Atoi(predicate); err == nil {
+		if n < 1 || n > len(nodes) {
+			return nil, nil
+		}
+		return []*xmlNode{nodes[n-1]}, nil
+	}
+	if predicate == "last()" {
+		if len(nodes) == 0 {
+			return nil, nil
+		}
+		return nodes[len(nodes)-1:], nil
+	}
+
+	name, want, hasValue := strings.Cut(predicate, "=")
+	name = strings.TrimSpace(name)
+	if hasValue {
+		want = strings.TrimSpace(want)
+		if len(want) < 2 || (want[0] != '\'' && want[0] != '"') || want[len(want)-1] != want[0] {
+			return nil, fmt.Errorf("predicate value must be quoted: %s", predicate)
+		}
+		want = want[1 : len(want)-1]
+	}
+	if name == "" {
+		return nil, fmt.Errorf("invalid predicate: %s", predicate)
+	}
+
+	var result []*xmlNode
+	for _, node := range nodes {
+		var values []string
+		if strings.HasPrefix(name, "@") {
+			if value, ok := node.attr(name[1:]); ok {
+				values = append(values, value)
+			}
+		} else {
+			for _, child := range node.children {
+				if child.name == name {
+					values = append(values, strings.TrimSpace(child.text.String()))
+				}
+			}
+		}
+		for _, value := range values {
+			if !hasValue || value == want {
+				result = append(result, node)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// dedupeNodes removes repeated nodes while keeping document order
+func dedupeNodes(nodes []*xmlNode) []*xmlNode {
+	seen := make(map[*xmlNode]bool, len(nodes))
+	result := nodes[:0:0]
+	for _, node := range nodes {
+		if !seen[node] {
+			seen[node] = true
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// charsetReader decodes the single-byte charsets common in tool output
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8":
+		return input, nil
+	case "iso-8859-1", "latin1", "latin-1", "us-ascii", "ascii":
+		return &latin1Reader{r: bufio.NewReader(input)}, nil
+	}
+	return nil, fmt.Errorf("unsupported charset: %s", charset)
+}
+
+// latin1Reader converts ISO-8859-1 bytes to UTF-8
+type latin1Reader struct {
+	r       *bufio.Reader
+	pending []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(l.pending) > 0 {
+			c := copy(p[n:], l.pending)
+			l.pending = l.pending[c:]
+			n += c
+			continue
+		}
+		b, err := l.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b < utf8.RuneSelf {
+			p[n] = b
+			n++
+			continue
+		}
+		l.pending = utf8.AppendRune(nil, rune(b))
+	}
+	return n, nil
+}
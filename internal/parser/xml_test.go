@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/charignon/umcp/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const svnLog = `<?xml version="1.0" encoding="UTF-8"?>
+<log>
+  <logentry revision="42">
+    <author>alice</author>
+    <date>2024-01-02T03:04:05Z</date>
+    <msg>Fix &amp; tidy</msg>
+  </logentry>
+  <logentry revision="41">
+    <author>bob</author>
+    <msg>Initial</msg>
+  </logentry>
+</log>
+`
+
+func TestParseXML(t *testing.T) {
+	result, err := parseXML(svnLog, "", nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"log": {
+			"logentry": [
+				{"@revision": "42", "author": "alice", "date": "2024-01-02T03:04:05Z", "msg": "Fix & tidy"},
+				{"@revision": "41", "author": "bob", "msg": "Initial"}
+			]
+		}
+	}`, result)
+}
+
+func TestParseXMLTextAndNamespaces(t *testing.T) {
+	input := `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+  <title type="text">News</title>
+  <media:thumbnail url="a.png"/>
+  <empty/>
+</feed>`
+
+	result, err := parseXML(input, "", nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"feed": {
+			"title": {"@type": "text", "#text": "News"},
+			"media:thumbnail": {"@url": "a.png"},
+			"empty": ""
+		}
+	}`, result)
+}
+
+func TestParseXMLSelect(t *testing.T) {
+	nmap := `<?xml version="1.0" encoding="ISO-8859-1"?>
+<!DOCTYPE nmaprun>
+<nmaprun scanner="nmap">
+  <host>
+    <address addr="10.0.0.1" addrtype="ipv4"/>
+    <ports>
+      <port protocol="tcp" portid="22"><state state="open"/><service name="ssh"/></port>
+      <port protocol="tcp" portid="80"><state state="closed"/><service name="http"/></port>
+    </ports>
+  </host>
+  <host>
+    <address addr="10.0.0.2" addrtype="ipv4"/>
+    <ports>
+      <port protocol="tcp" portid="443"><state state="open"/><service name="https"/></port>
+    </ports>
+  </host>
+</nmaprun>`
+
+	tests := []struct {
+		name     string
+		selector string
+		jq       string
+		expected string
+	}{
+		{name: "absolute path", selector: "/nmaprun/host/address/@addr", expected: `["10.0.0.1", "10.0.0.2"]`},
+		{name: "descendants", selector: "//port/@portid", expected: `["22", "80", "443"]`},
+		{name: "position", selector: "//host[2]/address", expected: `[{"@addr": "10.0.0.2", "@addrtype": "ipv4"}]`},
+		{name: "attribute predicate", selector: "//port[@portid='80']/service/@name", expected: `["http"]`},
+		{name: "wildcard", selector: "nmaprun/*/address/@addrtype", expected: `["ipv4", "ipv4"]`},
+		{name: "no match", selector: "//missing", expected: `[]`},
+		{name: "with jq", selector: "//port", jq: ".[0].service.\"@name\"", expected: `"ssh"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseXML(nmap, tt.selector, mustParseJQ(t, tt.jq))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+
+	result, err := parseXML(svnLog, "//logentry[author='bob']/msg/text()", nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["Initial"]`, result)
+}
+
+func TestParseXMLErrors(t *testing.T) {
+	_, err := parseXML("<a><b></a>", "", nil)
+	assert.Error(t, err)
+
+	_, err = parseXML("", "", nil)
+	assert.Error(t, err)
+
+	for _, selector := range []string{"//a[", "/a/@id/b", "a[@id=x]", "a//"} {
+		_, err := parseXML(svnLog, selector, nil)
+		assert.Error(t, err, selector)
+	}
+}
+
+func TestParseOutputXML(t *testing.T) {
+	result, err := ParseOutput(svnLog, &config.Output{Type: "xml", Select: "//author/text()"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `["alice", "bob"]`, result)
+}
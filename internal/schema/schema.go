@@ -0,0 +1,415 @@
+// Package schema validates decoded JSON values against a subset of JSON
+// Schema. Supported keywords: type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength,
+// pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum, allOf,
+// anyOf, oneOf and not. Annotations such as title and description are
+// ignored; $ref is not supported.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxErrors bounds how many problems a ValidationError reports
+const maxErrors = 10
+
+// ValidationError lists the places where a value does not match a schema
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Errors, "; ")
+}
+
+// patternCache holds compiled pattern keywords
+var patternCache sync.Map
+
+// Normalize converts a schema decoded from YAML or JSON to plain JSON
+// types, so numbers are float64 and objects are map[string]interface{}
+func Normalize(schema interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(toJSONCompatible(schema))
+	if err != nil {
+		return nil, fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("schema must be an object: %w", err)
+	}
+	return result, nil
+}
+
+// Check reports schema keywords with values of the wrong type, invalid
+// patterns and unsupported keywords
+func Check(schema map[string]interface{}) error {
+	return check(schema, "#")
+}
+
+// Validate checks a decoded JSON value against a normalized schema
+func Validate(value interface{}, schema map[string]interface{}) error {
+	var errs []string
+	validate(value, schema, "$", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) > maxErrors {
+		errs = append(errs[:maxErrors], fmt.Sprintf("and %d more", len(errs)-maxErrors))
+	}
+	return &ValidationError{Errors: errs}
+}
+
+func validate(value interface{}, schema map[string]interface{}, path string, errs *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(value, t) {
+		fail("expected %s, got %s", describeType(t), typeName(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if equal(value, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value %s is not one of %s", encode(value), encode(enum))
+		}
+	}
+	if c, ok := schema["const"]; ok && !equal(value, c) {
+		fail("value %s does not equal %s", encode(value), encode(c))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateObject(v, schema, path, errs)
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validate(item, items, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+		if min, ok := number(schema["minItems"]); ok && float64(len(v)) < min {
+			fail("expected at least %v items, got %d", min, len(v))
+		}
+		if max, ok := number(schema["maxItems"]); ok && float64(len(v)) > max {
+			fail("expected at most %v items, got %d", max, len(v))
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if min, ok := number(schema["minLength"]); ok && length < min {
+			fail("expected at least %v characters", min)
+		}
+		if max, ok := number(schema["maxLength"]); ok && length > max {
+			fail("expected at most %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := compilePattern(pattern); err == nil && !re.MatchString(v) {
+				fail("value %q does not match pattern %q", v, pattern)
+			}
+		}
+	case float64:
+		if min, ok := number(schema["minimum"]); ok && v < min {
+			fail("value %v is less than minimum %v", v, min)
+		}
+		if max, ok := number(schema["maximum"]); ok && v > max {
+			fail("value %v is greater than maximum %v", v, max)
+		}
+		if min, ok := number(schema["exclusiveMinimum"]); ok && v <= min {
+			fail("value %v must be greater than %v", v, min)
+		}
+		if max, ok := number(schema["exclusiveMaximum"]); ok && v >= max {
+			fail("value %v must be less than %v", v, max)
+		}
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if s, ok := sub.(map[string]interface{}); ok {
+				validate(value, s, path, errs)
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && countMatches(value, anyOf) == 0 {
+		fail("value does not match any schema in anyOf")
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if n := countMatches(value, oneOf); n != 1 {
+			fail("value matches %d schemas in oneOf, expected exactly 1", n)
+		}
+	}
+	if not, ok := schema["not"].(map[string]interface{}); ok && countMatches(value, []interface{}{not}) == 1 {
+		fail("value must not match the schema in not")
+	}
+}
+
+func validateObject(obj map[string]interface{}, schema map[string]interface{}, path string, errs *[]string) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, present := obj[key]; !present {
+					*errs = append(*errs, fmt.Sprintf("%s: missing required property %q", path, key))
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "." + key
+		if prop, ok := properties[key].(map[string]interface{}); ok {
+			validate(obj[key], prop, childPath, errs)
+			continue
+		}
+		if _, declared := properties[key]; declared {
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*errs = append(*errs, fmt.Sprintf("%s: unexpected property", childPath))
+			}
+		case map[string]interface{}:
+			validate(obj[key], additional, childPath, errs)
+		}
+	}
+}
+
+// countMatches returns how many of the schemas a value matches
+func countMatches(value interface{}, schemas []interface{}) int {
+	count := 0
+	for _, sub := range schemas {
+		s, ok := sub.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var errs []string
+		validate(value, s, "$", &errs)
+		if len(errs) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// matchesType reports whether value has the type, or one of the types, t
+func matchesType(value interface{}, t interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesTypeName(value, t)
+	case []interface{}:
+		for _, name := range t {
+			if s, ok := name.(string); ok && matchesTypeName(value, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesTypeName(value interface{}, name string) bool {
+	switch name {
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return typeName(value) == name
+	}
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func describeType(t interface{}) string {
+	if names, ok := t.([]interface{}); ok {
+		parts := make([]string, len(names))
+		for i, name := range names {
+			parts[i] = fmt.Sprintf("%v", name)
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprintf("%v", t)
+}
+
+func number(v interface{}) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
+
+func equal(a, b interface{}) bool {
+	return encode(a) == encode(b)
+}
+
+// encode renders a value as JSON; map keys are sorted so equal values
+// encode identically
+func encode(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+func check(schema map[string]interface{}, path string) error {
+	validTypes := map[string]bool{
+		"null": true, "boolean": true, "integer": true, "number": true,
+		"string": true, "array": true, "object": true,
+	}
+
+	for key, value := range schema {
+		at := path + "/" + key
+		switch key {
+		case "$ref":
+			return fmt.Errorf("%s: $ref is not supported", at)
+		case "type":
+			names := []interface{}{value}
+			if list, ok := value.([]interface{}); ok {
+				names = list
+			}
+			for _, name := range names {
+				s, ok := name.(string)
+				if !ok || !validTypes[s] {
+					return fmt.Errorf("%s: invalid type %v", at, name)
+				}
+			}
+		case "enum", "required":
+			if _, ok := value.([]interface{}); !ok {
+				return fmt.Errorf("%s: must be an array", at)
+			}
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s: must be an object", at)
+			}
+			for name, prop := range props {
+				sub, ok := prop.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("%s/%s: must be an object", at, name)
+				}
+				if err := check(sub, at+"/"+name); err != nil {
+					return err
+				}
+			}
+		case "items", "not":
+			sub, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s: must be an object", at)
+			}
+			if err := check(sub, at); err != nil {
+				return err
+			}
+		case "additionalProperties":
+			switch v := value.(type) {
+			case bool:
+			case map[string]interface{}:
+				if err := check(v, at); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%s: must be a boolean or an object", at)
+			}
+		case "allOf", "anyOf", "oneOf":
+			list, ok := value.([]interface{})
+			if !ok || len(list) == 0 {
+				return fmt.Errorf("%s: must be a non-empty array", at)
+			}
+			for i, item := range list {
+				sub, ok := item.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("%s/%d: must be an object", at, i)
+				}
+				if err := check(sub, fmt.Sprintf("%s/%d", at, i)); err != nil {
+					return err
+				}
+			}
+		case "minItems", "maxItems", "minLength", "maxLength",
+			"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+			if _, ok := number(value); !ok {
+				return fmt.Errorf("%s: must be a number", at)
+			}
+		case "pattern":
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("%s: must be a string", at)
+			}
+			if _, err := compilePattern(s); err != nil {
+				return fmt.Errorf("%s: invalid pattern: %w", at, err)
+			}
+		}
+	}
+	return nil
+}
+
+// toJSONCompatible converts YAML maps with non-string keys to string keys
+func toJSONCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[fmt.Sprintf("%v", key)] = toJSONCompatible(value)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[key] = toJSONCompatible(value)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = toJSONCompatible(value)
+		}
+		return result
+	default:
+		return v
+	}
+}
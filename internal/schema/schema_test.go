@@ -0,0 +1,138 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func mustSchema(t *testing.T, source string) map[string]interface{} {
+	t.Helper()
+	var raw interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(source), &raw))
+	s, err := Normalize(raw)
+	require.NoError(t, err)
+	require.NoError(t, Check(s))
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	s := mustSchema(t, `
+type: object
+required: [name, items]
+additionalProperties: false
+properties:
+  name: {type: string, minLength: 1, pattern: "^[a-z]+$"}
+  count: {type: integer, minimum: 0, maximum: 10}
+  status: {enum: [open, closed]}
+  items:
+    type: array
+    maxItems: 2
+    items:
+      type: object
+      properties:
+        id: {type: [integer, "null"]}
+  kind: {oneOf: [{const: a}, {const: b}]}
+`)
+
+	tests := []struct {
+		name   string
+		value  string
+		errors []string
+	}{
+		{
+			name:  "valid",
+			value: `{"name": "web", "count": 3, "status": "open", "items": [{"id": 1}, {"id": null}], "kind": "a"}`,
+		},
+		{
+			name:   "wrong root type",
+			value:  `[1, 2]`,
+			errors: []string{"$: expected object, got array"},
+		},
+		{
+			name:  "many problems",
+			value: `{"name": "Web", "count": 2.5, "status": "merged", "items": [{"id": "x"}, {}, {}], "extra": true, "kind": "c"}`,
+			errors: []string{
+				`$.count: expected integer, got number`,
+				`$.extra: unexpected property`,
+				`$.items[0].id: expected integer or null, got string`,
+				`$.items: expected at most 2 items, got 3`,
+				`$.kind: value matches 0 schemas in oneOf, expected exactly 1`,
+				`$.name: value "Web" does not match pattern "^[a-z]+$"`,
+				`$.status: value "merged" is not one of ["open","closed"]`,
+			},
+		},
+		{
+			name:   "missing required",
+			value:  `{"name": "web"}`,
+			errors: []string{`$: missing required property "items"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.value), &value))
+
+			err := Validate(value, s)
+			if tt.errors == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tt.errors, verr.Errors)
+		})
+	}
+}
+
+func TestValidateLimitsErrors(t *testing.T) {
+	s := mustSchema(t, `{type: array, items: {type: string}}`)
+	value := make([]interface{}, 15)
+	for i := range value {
+		value[i] = float64(i)
+	}
+
+	var verr *ValidationError
+	require.ErrorAs(t, Validate(value, s), &verr)
+	assert.Len(t, verr.Errors, maxErrors+1)
+	assert.Equal(t, "and 5 more", verr.Errors[maxErrors])
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{name: "unknown type", schema: `{type: text}`, err: "#/type: invalid type text"},
+		{name: "ref", schema: `{properties: {a: {$ref: "#/defs/a"}}}`, err: "#/properties/a/$ref: $ref is not supported"},
+		{name: "bad pattern", schema: `{pattern: "("}`, err: "#/pattern: invalid pattern"},
+		{name: "bad required", schema: `{required: name}`, err: "#/required: must be an array"},
+		{name: "empty anyOf", schema: `{anyOf: []}`, err: "#/anyOf: must be a non-empty array"},
+		{name: "annotations ignored", schema: `{title: x, description: y, $schema: z, type: object}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(tt.schema), &raw))
+			s, err := Normalize(raw)
+			require.NoError(t, err)
+
+			err = Check(s)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	_, err := Normalize([]interface{}{"not", "an", "object"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,210 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FileExporter writes spans to a file as JSON lines, one span per line
+type FileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// fileSpan is the JSON representation of a span in a span file
+type fileSpan struct {
+	TraceID      string                 `json:"trace_id"`
+	SpanID       string                 `json:"span_id"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
+	Name         string                 `json:"name"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	DurationMs   float64                `json:"duration_ms"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Status       string                 `json:"status"`
+	Message      string                 `json:"message,omitempty"`
+}
+
+// NewFileExporter creates an exporter appending to the given file
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open span file: %w", err)
+	}
+	return &FileExporter{file: file}, nil
+}
+
+// ExportSpans appends the spans to the file
+func (e *FileExporter) ExportSpans(spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, span := range spans {
+		status := "ok"
+		if span.Error {
+			status = "error"
+		}
+		data, err := json.Marshal(fileSpan{
+			TraceID:      span.TraceID,
+			SpanID:       span.SpanID,
+			ParentSpanID: span.ParentSpanID,
+			Name:         span.Name,
+			Start:        span.StartTime,
+			End:          span.EndTime,
+			DurationMs:   float64(span.EndTime.Sub(span.StartTime).Microseconds()) / 1000,
+			Attributes:   span.Attributes,
+			Status:       status,
+			Message:      span.StatusMessage,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal span: %w", err)
+		}
+		if _, err := fmt.Fprintf(e.file, "%s\n", data); err != nil {
+			return fmt.Errorf("failed to write span: %w", err)
+		}
+	}
+	return nil
+}
+
+// Shutdown closes the span file
+func (e *FileExporter) Shutdown() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+// OTLPExporter sends spans to an OpenTelemetry collector using OTLP/HTTP
+// with JSON encoding
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter creates an exporter for the given collector endpoint. If
+// the endpoint has no path, the standard /v1/traces path is used.
+func NewOTLPExporter(endpoint, serviceName string) (*OTLPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %s", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+
+	return &OTLPExporter{
+		endpoint:    u.String(),
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+// ExportSpans posts the spans to the collector
+func (e *OTLPExporter) ExportSpans(spans []*Span) error {
+	otlpSpans := make([]map[string]interface{}, 0, len(spans))
+	for _, span := range spans {
+		status := map[string]interface{}{"code": 1} // STATUS_CODE_OK
+		if span.Error {
+			status = map[string]interface{}{"code": 2, "message": span.StatusMessage} // STATUS_CODE_ERROR
+		}
+
+		otlpSpan := map[string]interface{}{
+			"traceId":           span.TraceID,
+			"spanId":            span.SpanID,
+			"name":              span.Name,
+			"kind":              1, // SPAN_KIND_INTERNAL
+			"startTimeUnixNano": strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.Attributes),
+			"status":            status,
+		}
+		if span.ParentSpanID != "" {
+			otlpSpan["parentSpanId"] = span.ParentSpanID
+		} else {
+			otlpSpan["kind"] = 2 // SPAN_KIND_SERVER
+		}
+		otlpSpans = append(otlpSpans, otlpSpan)
+	}
+
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{
+						"service.name": e.serviceName,
+					}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "umcp"},
+						"spans": otlpSpans,
+					},
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal OTLP payload: %w", err)
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to send spans: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Shutdown is a no-op; spans are sent synchronously
+func (e *OTLPExporter) Shutdown() error {
+	return nil
+}
+
+// otlpAttributes converts attributes to OTLP KeyValue form
+func otlpAttributes(attrs map[string]interface{}) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(attrs))
+	for key, value := range attrs {
+		result = append(result, map[string]interface{}{
+			"key":   key,
+			"value": otlpValue(value),
+		})
+	}
+	return result
+}
+
+// otlpValue converts a Go value to an OTLP AnyValue
+func otlpValue(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case string:
+		return map[string]interface{}{"stringValue": v}
+	case bool:
+		return map[string]interface{}{"boolValue": v}
+	case int:
+		return map[string]interface{}{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": v}
+	case []string:
+		values := make([]interface{}, 0, len(v))
+		for _, s := range v {
+			values = append(values, map[string]interface{}{"stringValue": s})
+		}
+		return map[string]interface{}{"arrayValue": map[string]interface{}{"values": values}}
+	default:
+		return map[string]interface{}{"stringValue": fmt.Sprintf("%v", v)}
+	}
+}
@@ -0,0 +1,264 @@
+package telemetry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/charignon/umcp/internal/executor"
+	"github.com/rs/zerolog/log"
+)
+
+// Span is a single timed operation within a trace
+type Span struct {
+	TraceID       string
+	SpanID        string
+	ParentSpanID  string
+	Name          string
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]interface{}
+	Error         bool
+	StatusMessage string
+
+	tracer *Tracer
+}
+
+// Exporter ships finished spans to a backend
+type Exporter interface {
+	ExportSpans(spans []*Span) error
+	Shutdown() error
+}
+
+// Tracer records spans for JSON-RPC requests and the command stages they
+// trigger. It implements executor.Tracer and executor.StageTracer so it can
+// be registered directly on a CommandExecutor.
+//
+// The server handles one request at a time, so the tracer keeps a stack of
+// active spans and parents new spans on the innermost one. A nil *Tracer is
+// valid and records nothing.
+type Tracer struct {
+	mu        sync.Mutex
+	exporters []Exporter
+	active    []*Span
+	finished  []*Span
+	command   map[string]interface{}
+}
+
+// NewTracer creates a tracer that exports to the given exporters
+func NewTracer(exporters ...Exporter) *Tracer {
+	return &Tracer{exporters: exporters}
+}
+
+// StartSpan starts a span as a child of the innermost active span and makes
+// it the active span until End is called
+func (t *Tracer) StartSpan(name string, attrs map[string]interface{}) *Span {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := t.newSpan(name, time.Now(), attrs)
+	t.active = append(t.active, span)
+	return span
+}
+
+// Current returns the innermost active span, or nil if there is none
+func (t *Tracer) Current() *Span {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.active) == 0 {
+		return nil
+	}
+	return t.active[len(t.active)-1]
+}
+
+// SetAttribute sets an attribute on the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.Attributes[key] = value
+}
+
+// RecordError marks the span as failed
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.Error = true
+	s.StatusMessage = err.Error()
+}
+
+// End finishes the span. Ending a root span exports the whole trace.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	t := s.tracer
+	t.mu.Lock()
+	s.EndTime = time.Now()
+	for i := len(t.active) - 1; i >= 0; i-- {
+		if t.active[i] == s {
+			t.active = append(t.active[:i], t.active[i+1:]...)
+			break
+		}
+	}
+	t.finished = append(t.finished, s)
+
+	var batch []*Span
+	if s.ParentSpanID == "" {
+		batch = t.takeTrace(s.TraceID)
+	}
+	t.mu.Unlock()
+
+	if len(batch) > 0 {
+		t.export(batch)
+	}
+}
+
+// TraceCommand remembers the command line so it can be attached to the
+// following exec stage span
+func (t *Tracer) TraceCommand(command string, args []string, workingDir string, env []string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.command = map[string]interface{}{
+		"process.executable.name":   command,
+		"process.command_args":      args,
+		"process.working_directory": workingDir,
+	}
+}
+
+// TraceCommandOutput is a no-op; exit code and output size arrive with the
+// exec stage
+func (t *Tracer) TraceCommandOutput(output string, exitCode int, err error) {}
+
+// TraceStage records a completed execution stage as a child span
+func (t *Tracer) TraceStage(stage executor.Stage) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	attrs := map[string]interface{}{
+		"umcp.config": stage.Config,
+		"umcp.tool":   stage.Tool,
+	}
+	for k, v := range stage.Attributes {
+		attrs[k] = v
+	}
+	if stage.Name == "exec" {
+		for k, v := range t.command {
+			attrs[k] = v
+		}
+		t.command = nil
+	}
+
+	span := t.newSpan(stage.Name, stage.Start, attrs)
+	span.EndTime = stage.Start.Add(stage.Duration)
+	if stage.Err != nil {
+		span.Error = true
+		span.StatusMessage = stage.Err.Error()
+	}
+	t.finished = append(t.finished, span)
+}
+
+// Close flushes any unfinished spans and shuts down the exporters
+func (t *Tracer) Close() error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	batch := t.finished
+	t.finished = nil
+	t.mu.Unlock()
+
+	if len(batch) > 0 {
+		t.export(batch)
+	}
+
+	var firstErr error
+	for _, exporter := range t.exporters {
+		if err := exporter.Shutdown(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// newSpan creates a span parented on the innermost active span. Callers must
+// hold t.mu.
+func (t *Tracer) newSpan(name string, start time.Time, attrs map[string]interface{}) *Span {
+	span := &Span{
+		SpanID:     randomHex(8),
+		Name:       name,
+		StartTime:  start,
+		Attributes: make(map[string]interface{}, len(attrs)),
+		tracer:     t,
+	}
+	for k, v := range attrs {
+		span.Attributes[k] = v
+	}
+
+	if len(t.active) > 0 {
+		parent := t.active[len(t.active)-1]
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	} else {
+		span.TraceID = randomHex(16)
+	}
+	return span
+}
+
+// takeTrace removes and returns the finished spans of a trace. Callers must
+// hold t.mu.
+func (t *Tracer) takeTrace(traceID string) []*Span {
+	var batch, rest []*Span
+	for _, span := range t.finished {
+		if span.TraceID == traceID {
+			batch = append(batch, span)
+		} else {
+			rest = append(rest, span)
+		}
+	}
+	t.finished = rest
+	return batch
+}
+
+// export sends spans to every exporter, logging failures
+func (t *Tracer) export(spans []*Span) {
+	for _, exporter := range t.exporters {
+		if err := exporter.ExportSpans(spans); err != nil {
+			log.Warn().Err(err).Msg("Failed to export spans")
+		}
+	}
+}
+
+// randomHex returns n random bytes hex encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,180 @@
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charignon/umcp/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryExporter struct {
+	spans []*Span
+}
+
+func (e *memoryExporter) ExportSpans(spans []*Span) error {
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memoryExporter) Shutdown() error { return nil }
+
+func TestSpanHierarchy(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := NewTracer(exporter)
+
+	root := tracer.StartSpan("tools/call", map[string]interface{}{"rpc.method": "tools/call"})
+	root.SetAttribute("umcp.tool", "git_status")
+
+	tracer.TraceCommand("git", []string{"status"}, "/repo", nil)
+	for _, name := range []string{"build", "sandbox", "exec", "parse"} {
+		tracer.TraceStage(executor.Stage{
+			Name:       name,
+			Config:     "git",
+			Tool:       "status",
+			Start:      time.Now(),
+			Duration:   time.Millisecond,
+			Attributes: map[string]interface{}{"exit_code": 0},
+		})
+	}
+
+	// Nothing is exported until the root span ends
+	assert.Empty(t, exporter.spans)
+	root.End()
+
+	require.Len(t, exporter.spans, 5)
+	byName := map[string]*Span{}
+	for _, span := range exporter.spans {
+		byName[span.Name] = span
+		assert.Equal(t, root.TraceID, span.TraceID)
+	}
+
+	assert.Empty(t, byName["tools/call"].ParentSpanID)
+	assert.Equal(t, "git_status", byName["tools/call"].Attributes["umcp.tool"])
+	for _, name := range []string{"build", "sandbox", "exec", "parse"} {
+		assert.Equal(t, root.SpanID, byName[name].ParentSpanID, name)
+		assert.Equal(t, "git", byName[name].Attributes["umcp.config"])
+		assert.Equal(t, "status", byName[name].Attributes["umcp.tool"])
+	}
+	assert.Equal(t, "/repo", byName["exec"].Attributes["process.working_directory"])
+	assert.NotContains(t, byName["parse"].Attributes, "process.working_directory")
+}
+
+func TestSpanError(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := NewTracer(exporter)
+
+	span := tracer.StartSpan("tools/call", nil)
+	tracer.TraceStage(executor.Stage{Name: "sandbox", Start: time.Now(), Err: errors.New("blocked")})
+	span.RecordError(errors.New("failed"))
+	span.End()
+
+	require.Len(t, exporter.spans, 2)
+	for _, s := range exporter.spans {
+		assert.True(t, s.Error)
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.StartSpan("initialize", nil)
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("ignored"))
+	span.End()
+	tracer.TraceStage(executor.Stage{Name: "exec"})
+	assert.Nil(t, tracer.Current())
+	assert.NoError(t, tracer.Close())
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := NewFileExporter(path)
+	require.NoError(t, err)
+
+	tracer := NewTracer(exporter)
+	span := tracer.StartSpan("tools/call", nil)
+	tracer.TraceStage(executor.Stage{Name: "exec", Start: time.Now(), Duration: time.Second})
+	span.End()
+	require.NoError(t, tracer.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []fileSpan
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record fileSpan
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, "exec", records[0].Name)
+	assert.Equal(t, float64(1000), records[0].DurationMs)
+	assert.Equal(t, "ok", records[1].Status)
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var received map[string]interface{}
+	var path string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	exporter, err := NewOTLPExporter(collector.URL, "umcp")
+	require.NoError(t, err)
+
+	tracer := NewTracer(exporter)
+	span := tracer.StartSpan("tools/call", map[string]interface{}{"umcp.tool": "ls_list"})
+	tracer.TraceStage(executor.Stage{
+		Name:       "exec",
+		Start:      time.Now(),
+		Attributes: map[string]interface{}{"exit_code": 2},
+		Err:        errors.New("exit 2"),
+	})
+	span.End()
+
+	assert.Equal(t, "/v1/traces", path)
+	resourceSpans := received["resourceSpans"].([]interface{})
+	require.Len(t, resourceSpans, 1)
+	scopeSpans := resourceSpans[0].(map[string]interface{})["scopeSpans"].([]interface{})
+	spans := scopeSpans[0].(map[string]interface{})["spans"].([]interface{})
+	require.Len(t, spans, 2)
+
+	exec := spans[0].(map[string]interface{})
+	assert.Equal(t, "exec", exec["name"])
+	assert.Equal(t, span.SpanID, exec["parentSpanId"])
+	assert.Equal(t, float64(2), exec["status"].(map[string]interface{})["code"])
+	assert.Contains(t, exec["attributes"], map[string]interface{}{
+		"key":   "exit_code",
+		"value": map[string]interface{}{"intValue": "2"},
+	})
+}
+
+func TestOTLPExporterErrors(t *testing.T) {
+	_, err := NewOTLPExporter("not a url", "umcp")
+	assert.Error(t, err)
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	exporter, err := NewOTLPExporter(collector.URL+"/custom", "umcp")
+	require.NoError(t, err)
+	err = exporter.ExportSpans([]*Span{{Name: "x", Attributes: map[string]interface{}{}}})
+	assert.ErrorContains(t, err, "503")
+}
@@ -0,0 +1,215 @@
+// Package template renders argument templates. Templates use Go
+// text/template syntax with the call's argument values as data: {{.name}}
+// is an argument value, and ${name} is accepted as a shorthand for it.
+// Besides the text/template builtins (if, eq, printf, ...) the functions
+// join, split, default, lower, upper, trim and replace are available.
+package template
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	gotemplate "text/template"
+	goparse "text/template/parse"
+)
+
+// legacyPattern matches ${name} placeholders
+var legacyPattern = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// cache holds parsed templates by source text
+var cache sync.Map
+
+var funcs = gotemplate.FuncMap{
+	// join renders a list as one string: {{join .fields ","}}
+	"join": func(value interface{}, sep string) string {
+		return strings.Join(Strings(value), sep)
+	},
+	// split turns a string into a list: {{range split .paths ":"}}
+	"split": func(value interface{}, sep string) []string {
+		s := toString(value)
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, sep)
+	},
+	// default returns fallback when value is empty: {{default "HEAD" .ref}}
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || toString(value) == "" {
+			return fallback
+		}
+		return value
+	},
+	"lower": func(value interface{}) string { return strings.ToLower(toString(value)) },
+	"upper": func(value interface{}) string { return strings.ToUpper(toString(value)) },
+	"trim":  func(value interface{}) string { return strings.TrimSpace(toString(value)) },
+	"replace": func(old, new string, value interface{}) string {
+		return strings.ReplaceAll(toString(value), old, new)
+	},
+}
+
+// Check parses a template and reports syntax errors
+func Check(text string) error {
+	_, err := parse(text)
+	return err
+}
+
+// Render expands a template. Arguments missing from data render as empty
+// strings; ${name} placeholders for names not in data are left as is.
+func Render(text string, data map[string]interface{}) (string, error) {
+	if !strings.Contains(text, "{{") && !strings.Contains(text, "${") {
+		return text, nil
+	}
+
+	// ${name} placeholders become {{index . "name"}} when name is known
+	text = legacyPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := legacyPattern.FindStringSubmatch(placeholder)[1]
+		if _, ok := data[name]; ok {
+			return fmt.Sprintf("{{index . %q}}", name)
+		}
+		return placeholder
+	})
+
+	tmpl, err := parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	// Missing and null values render as "" rather than "<no value>"
+	values := make(map[string]interface{}, len(data))
+	for _, name := range fieldNames(tmpl.Root) {
+		values[name] = ""
+	}
+	for key, value := range data {
+		if value == nil {
+			value = ""
+		}
+		values[key] = plainNumbers(value)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, values); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", text, err)
+	}
+	return sb.String(), nil
+}
+
+// fieldNames returns the names a template looks up as {{.name}} or
+// {{$.name}}. Fields looked up inside range and with blocks are included
+// too; giving them a value does no harm.
+func fieldNames(node goparse.Node) []string {
+	var names []string
+	var walk func(node goparse.Node)
+	walk = func(node goparse.Node) {
+		switch n := node.(type) {
+		case *goparse.ListNode:
+			if n != nil {
+				for _, child := range n.Nodes {
+					walk(child)
+				}
+			}
+		case *goparse.ActionNode:
+			walk(n.Pipe)
+		case *goparse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *goparse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *goparse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *goparse.TemplateNode:
+			walk(n.Pipe)
+		case *goparse.PipeNode:
+			if n != nil {
+				for _, cmd := range n.Cmds {
+					walk(cmd)
+				}
+			}
+		case *goparse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *goparse.ChainNode:
+			walk(n.Node)
+		case *goparse.FieldNode:
+			names = append(names, n.Ident[0])
+		case *goparse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				names = append(names, n.Ident[1])
+			}
+		}
+	}
+	walk(node)
+	return names
+}
+
+// Strings converts a list value to strings. Scalars become a single item
+// and empty values an empty list.
+func Strings(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, len(v))
+		for i, item := range v {
+			result[i] = toString(item)
+		}
+		return result
+	case string:
+		if v == "" {
+			return nil
+		}
+	}
+	return []string{toString(value)}
+}
+
+func parse(text string) (*gotemplate.Template, error) {
+	if tmpl, ok := cache.Load(text); ok {
+		return tmpl.(*gotemplate.Template), nil
+	}
+	tmpl, err := gotemplate.New("argument").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	cache.Store(text, tmpl)
+	return tmpl, nil
+}
+
+// plainNumbers converts JSON numbers so they print without exponents
+func plainNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return int64(v)
+		}
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = plainNumbers(item)
+		}
+		return result
+	}
+	return value
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
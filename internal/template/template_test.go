@@ -0,0 +1,61 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	data := map[string]interface{}{
+		"days":   float64(7),
+		"ratio":  0.25,
+		"fields": []interface{}{"hash", float64(3)},
+		"ref":    nil,
+		"name":   " Main ",
+		"paths":  "a:b",
+		"note":   "<no value>",
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "plain text", text: "--oneline", expected: "--oneline"},
+		{name: "value", text: "--since={{.days}}d", expected: "--since=7d"},
+		{name: "float", text: "{{.ratio}}", expected: "0.25"},
+		{name: "legacy placeholder", text: "--since=${days}d", expected: "--since=7d"},
+		{name: "unknown placeholder left alone", text: "${HOME}/${days}", expected: "${HOME}/7"},
+		{name: "join", text: `--format {{join .fields ","}}`, expected: "--format hash,3"},
+		{name: "missing and null values", text: "[{{.ref}}{{.other}}{{$.gone}}]", expected: "[]"},
+		{name: "missing value in block", text: `{{if .days}}[{{.other}}]{{end}}`, expected: "[]"},
+		{name: "value kept verbatim", text: "{{.note}}", expected: "<no value>"},
+		{name: "default", text: `{{default "HEAD" .ref}}`, expected: "HEAD"},
+		{name: "string functions", text: `{{trim .name | lower}}-{{upper "x"}}-{{replace "/" "-" "a/b"}}`, expected: "main-X-a-b"},
+		{name: "split and range", text: `{{range split .paths ":"}}<{{.}}>{{end}}`, expected: "<a><b>"},
+		{name: "conditional", text: `{{if .days}}--recent{{end}}{{if .ref}}--ref{{end}}`, expected: "--recent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Render(tt.text, data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check(`{{join .fields ","}}`))
+	assert.ErrorContains(t, Check("{{.days"), "invalid template")
+	assert.ErrorContains(t, Check("{{nope .days}}"), `function "nope" not defined`)
+}
+
+func TestStrings(t *testing.T) {
+	assert.Equal(t, []string{"a", "2", "1.5"}, Strings([]interface{}{"a", float64(2), 1.5}))
+	assert.Equal(t, []string{"one"}, Strings("one"))
+	assert.Empty(t, Strings(""))
+	assert.Empty(t, Strings(nil))
+}
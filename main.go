@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/charignon/umcp/internal/audit"
 	"github.com/charignon/umcp/internal/config"
 	"github.com/charignon/umcp/internal/logger"
 	"github.com/charignon/umcp/internal/mcp"
@@ -16,6 +17,10 @@ import (
 var version = "1.0.0"
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(runAudit(os.Args[2:]))
+	}
+
 	var (
 		configPaths     stringSlice
 		workingDir      string
@@ -28,6 +33,10 @@ func main() {
 		debugMode       bool
 		debugTrace      string
 		replayTrace     string
+		spanFile        string
+		otlpEndpoint    string
+		metricsListen   string
+		auditLog        string
 	)
 
 	flag.Var(&configPaths, "config", "Path to YAML configuration file (can be specified multiple times)")
@@ -41,6 +50,10 @@ func main() {
 	flag.BoolVar(&debugMode, "debug", false, "Enable debug mode with message tracing")
 	flag.StringVar(&debugTrace, "debug-trace", "", "File to save debug trace (enables debug mode)")
 	flag.StringVar(&replayTrace, "replay-trace", "", "File to replay debug trace from")
+	flag.StringVar(&spanFile, "span-file", "", "File to write tool call spans to as JSON lines")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "OTLP/HTTP collector URL to export tool call spans to")
+	flag.StringVar(&metricsListen, "metrics-listen", "", "Address to serve Prometheus metrics on (e.g. :9090)")
+	flag.StringVar(&auditLog, "audit-log", "", "File to append a hash-chained audit record of every tool call to")
 	flag.Parse()
 
 	if showVersion {
@@ -76,6 +89,13 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Pin the binaries the configs run before serving any call
+	for i, cfg := range configs {
+		if err := cfg.Preflight(); err != nil {
+			log.Fatal().Err(err).Str("config", configPaths[i]).Msg("Preflight check failed")
+		}
+	}
+
 	// Enable debug mode if specified
 	if debugTrace != "" {
 		debugMode = true
@@ -83,9 +103,13 @@ func main() {
 
 	// Create and run MCP server
 	server := mcp.NewServer(configs, mcp.ServerOptions{
-		DebugMode:   debugMode,
-		DebugTrace:  debugTrace,
-		ReplayTrace: replayTrace,
+		DebugMode:     debugMode,
+		DebugTrace:    debugTrace,
+		ReplayTrace:   replayTrace,
+		SpanFile:      spanFile,
+		OTLPEndpoint:  otlpEndpoint,
+		MetricsListen: metricsListen,
+		AuditLog:      auditLog,
 	})
 
 	if testMode {
@@ -118,6 +142,23 @@ func generateClaudeConfig(configs []*config.Config, paths []string) {
 	fmt.Println("}")
 }
 
+// runAudit implements the "umcp audit" subcommands
+func runAudit(args []string) int {
+	if len(args) != 2 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, "usage: umcp audit verify <audit-log>")
+		return 2
+	}
+
+	count, err := audit.VerifyFile(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Audit log verification failed after %d valid records: %v\n", count, err)
+		return 1
+	}
+
+	fmt.Printf("Audit log OK: %d records\n", count)
+	return 0
+}
+
 type stringSlice []string
 
 func (s *stringSlice) String() string {